@@ -4,7 +4,10 @@ import (
 	"crypto/ed25519"
 	"crypto/rand"
 	"encoding/hex"
+	"fmt"
 	"io"
+
+	"github.com/DenisBytes/GoChain/errs"
 )
 
 const (
@@ -85,13 +88,13 @@ func (p *PublicKey) Address() Address {
 	}
 }
 
-func PublicKeyFromBytes(b []byte) *PublicKey {
+func PublicKeyFromBytes(b []byte) (*PublicKey, error) {
 	if len(b) != PubKeyLen {
-		panic("invalid bytes")
+		return nil, fmt.Errorf("%w: public key of %d bytes", errs.ErrMalformed, len(b))
 	}
 	return &PublicKey{
 		key: ed25519.PublicKey(b),
-	}
+	}, nil
 }
 
 // Signature
@@ -107,13 +110,13 @@ func (s *Signature) Verify(pubKey *PublicKey, msg []byte) bool {
 	return ed25519.Verify(pubKey.key, msg, s.value)
 }
 
-func SignatureFromBytes(b []byte) *Signature {
+func SignatureFromBytes(b []byte) (*Signature, error) {
 	if len(b) != SignatureLen {
-		panic("length of bytes should be 64")
+		return nil, fmt.Errorf("%w: signature of %d bytes", errs.ErrMalformed, len(b))
 	}
 	return &Signature{
 		value: b,
-	}
+	}, nil
 }
 
 // Address
@@ -129,11 +132,11 @@ func (a Address) String() string {
 	return hex.EncodeToString(a.value)
 }
 
-func AddressFromBytes(b []byte) Address {
+func AddressFromBytes(b []byte) (Address, error) {
 	if len(b) != AddressLen {
-		panic("length of bytes should be 20")
+		return Address{}, fmt.Errorf("%w: address of %d bytes", errs.ErrMalformed, len(b))
 	}
 	return Address{
 		value: b,
-	}
+	}, nil
 }
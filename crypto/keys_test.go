@@ -4,6 +4,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/DenisBytes/GoChain/errs"
 )
 
 func TestGeneratePrivateKey(t *testing.T) {
@@ -49,3 +51,24 @@ func TestPublicKeyToAddress(t *testing.T) {
 	address := pubKey.Address()
 	assert.Equal(t, AddressLen, len(address.Bytes()))
 }
+
+func TestFromBytesRejectsMalformedInput(t *testing.T) {
+	privKey := GeneratePrivateKey()
+	sig := privKey.Sign([]byte("foo"))
+
+	pubKey, err := PublicKeyFromBytes(privKey.Public().Bytes())
+	assert.Nil(t, err)
+	assert.True(t, sig.Verify(pubKey, []byte("foo")))
+	_, err = PublicKeyFromBytes(privKey.Public().Bytes()[1:])
+	assert.ErrorIs(t, err, errs.ErrMalformed)
+
+	_, err = SignatureFromBytes(sig.Bytes())
+	assert.Nil(t, err)
+	_, err = SignatureFromBytes(append(sig.Bytes(), 0))
+	assert.ErrorIs(t, err, errs.ErrMalformed)
+
+	_, err = AddressFromBytes(pubKey.Address().Bytes())
+	assert.Nil(t, err)
+	_, err = AddressFromBytes(nil)
+	assert.ErrorIs(t, err, errs.ErrMalformed)
+}
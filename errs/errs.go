@@ -0,0 +1,117 @@
+// Package errs defines the errors returned when decoding and validating
+// blocks and transactions. Every error wraps one of the sentinels below,
+// so callers can tell them apart with errors.Is whatever the details,
+// and the ones about a single transaction or block are a TxError or a
+// BlockError, which errors.As can extract.
+package errs
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors about the encoding of a message or a request.
+var (
+	ErrMalformed      = errors.New("malformed encoding")
+	ErrInvalidRequest = errors.New("invalid request")
+	ErrNotFound       = errors.New("not found")
+)
+
+// Errors returned when validating a transaction.
+var (
+	ErrMalformedTx       = errors.New("malformed transaction")
+	ErrTxTooLarge        = errors.New("transaction too large")
+	ErrInvalidSignature  = errors.New("invalid signature")
+	ErrMissingInput      = errors.New("missing input")
+	ErrDoubleSpend       = errors.New("double spend")
+	ErrImmatureCoinbase  = errors.New("immature coinbase")
+	ErrNotOwner          = errors.New("input not signed by the output owner")
+	ErrInsufficientFunds = errors.New("insufficient funds")
+)
+
+// Errors returned when validating a block.
+var (
+	ErrDuplicateBlock  = errors.New("block already known")
+	ErrInvalidBranch   = errors.New("block extends an invalid branch")
+	ErrBadPrevHash     = errors.New("block does not extend the tip")
+	ErrBadMerkleRoot   = errors.New("bad merkle root")
+	ErrBadCoinbase     = errors.New("bad coinbase")
+	ErrUnknownVersion  = errors.New("unknown block version")
+	ErrBadHeight       = errors.New("bad block height")
+	ErrTimestampTooOld = errors.New("block timestamp not after the median time of the previous blocks")
+	ErrTimestampTooNew = errors.New("block timestamp too far in the future")
+	ErrBlockTooLarge   = errors.New("block too large")
+	ErrTooManyTxs      = errors.New("too many transactions in block")
+	ErrWrongProposer   = errors.New("block not signed by the proposer of its height")
+)
+
+// TxError is a transaction that failed validation.
+type TxError struct {
+	// Hash is the hex encoded hash of the transaction.
+	Hash string
+	// Input is the index of the offending input, or -1 when the error
+	// is not about a single input.
+	Input int
+	// Err is the sentinel telling what is wrong.
+	Err error
+	// Reason gives the details, if any.
+	Reason string
+}
+
+// TxErrorf returns a TxError for the transaction with the given hash.
+func TxErrorf(hash string, input int, err error, format string, args ...any) error {
+	return &TxError{
+		Hash:   hash,
+		Input:  input,
+		Err:    err,
+		Reason: fmt.Sprintf(format, args...),
+	}
+}
+
+func (e *TxError) Error() string {
+	msg := fmt.Sprintf("tx %s", e.Hash)
+	if e.Input >= 0 {
+		msg += fmt.Sprintf(" input %d", e.Input)
+	}
+	msg += ": " + e.Err.Error()
+	if e.Reason != "" {
+		msg += ": " + e.Reason
+	}
+	return msg
+}
+
+func (e *TxError) Unwrap() error {
+	return e.Err
+}
+
+// BlockError is a block that failed validation. Err may be a TxError for
+// a block holding an invalid transaction.
+type BlockError struct {
+	// Hash is the hex encoded hash of the block.
+	Hash   string
+	Height int
+	Err    error
+	Reason string
+}
+
+// BlockErrorf returns a BlockError for the block with the given hash.
+func BlockErrorf(hash string, height int, err error, format string, args ...any) error {
+	return &BlockError{
+		Hash:   hash,
+		Height: height,
+		Err:    err,
+		Reason: fmt.Sprintf(format, args...),
+	}
+}
+
+func (e *BlockError) Error() string {
+	msg := fmt.Sprintf("block %s at height %d: %s", e.Hash, e.Height, e.Err.Error())
+	if e.Reason != "" {
+		msg += ": " + e.Reason
+	}
+	return msg
+}
+
+func (e *BlockError) Unwrap() error {
+	return e.Err
+}
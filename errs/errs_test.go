@@ -0,0 +1,32 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorsUnwrap(t *testing.T) {
+	txErr := TxErrorf("abcd", 1, ErrDoubleSpend, "spends %s twice", "ef_0")
+	require.Equal(t, "tx abcd input 1: double spend: spends ef_0 twice", txErr.Error())
+	require.ErrorIs(t, txErr, ErrDoubleSpend)
+	require.NotErrorIs(t, txErr, ErrMissingInput)
+
+	blockErr := BlockErrorf("0123", 7, txErr, "")
+	require.Equal(t, "block 0123 at height 7: tx abcd input 1: double spend: spends ef_0 twice", blockErr.Error())
+	wrapped := fmt.Errorf("reorganisation failed: %w", blockErr)
+	require.ErrorIs(t, wrapped, ErrDoubleSpend)
+
+	var (
+		asTx    *TxError
+		asBlock *BlockError
+	)
+	require.True(t, errors.As(wrapped, &asTx))
+	require.Equal(t, 1, asTx.Input)
+	require.True(t, errors.As(wrapped, &asBlock))
+	require.Equal(t, 7, asBlock.Height)
+
+	require.Equal(t, "tx abcd: invalid signature", TxErrorf("abcd", -1, ErrInvalidSignature, "").Error())
+}
@@ -3,7 +3,6 @@ module github.com/DenisBytes/GoChain
 go 1.21.6
 
 require (
-	github.com/cbergoon/merkletree v0.2.0 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/golang/protobuf v1.5.3 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
@@ -2,7 +2,12 @@ package main
 
 import (
 	"context"
+	"encoding/hex"
+	"fmt"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"google.golang.org/grpc"
@@ -14,27 +19,77 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "genesis" {
+		printGenesisHash(os.Args[2:])
+		return
+	}
+
+	// The first two nodes are the validators of a fresh network and take
+	// turns producing blocks.
+	keys := []*crypto.PrivateKey{crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey()}
+	genesis := node.DevGenesis()
+	genesis.Validators = nil
+	for _, key := range keys {
+		genesis.Validators = append(genesis.Validators, hex.EncodeToString(key.Public().Bytes()))
+	}
 
-	makeNode(":3000", []string{}, true)
+	nodes := []*node.Node{makeNode(":3000", []string{}, genesis, keys[0])}
 	time.Sleep(time.Second)
-	makeNode(":4000", []string{":3000"}, false)
+	nodes = append(nodes, makeNode(":4000", []string{":3000"}, genesis, keys[1]))
 	time.Sleep(time.Second)
-	makeNode(":5000", []string{":4000"}, false)
+	nodes = append(nodes, makeNode(":5000", []string{":4000"}, genesis, nil))
+
+	// Stop the nodes on interrupt so they save their mempool and close
+	// their database.
+	sigch := make(chan os.Signal, 1)
+	signal.Notify(sigch, os.Interrupt, syscall.SIGTERM)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
 	for {
-		time.Sleep(time.Second)
-		makeTransaction()
+		select {
+		case <-ticker.C:
+			makeTransaction()
+		case <-sigch:
+			for _, n := range nodes {
+				if err := n.Stop(); err != nil {
+					log.Println(err)
+				}
+			}
+			return
+		}
+	}
+}
+
+// printGenesisHash generates the genesis block of the specification
+// given as argument and prints its hash, for the operators of a network
+// to check they start from the same one.
+func printGenesisHash(args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: gochain genesis <genesis.json>")
+		os.Exit(2)
+	}
+	g, err := node.LoadGenesis(args[0])
+	if err != nil {
+		log.Fatal(err)
+	}
+	hash, err := g.Hash()
+	if err != nil {
+		log.Fatal(err)
 	}
+	fmt.Println(hash)
 }
 
-func makeNode(listenAddr string, bootstrapNodes []string, isValidator bool) *node.Node {
+func makeNode(listenAddr string, bootstrapNodes []string, genesis *node.Genesis, privKey *crypto.PrivateKey) *node.Node {
 	cfg := node.ServerConfig{
 		Version:    "gochain-0.1",
 		ListenAddr: listenAddr,
+		PrivateKy:  privKey,
+		Genesis:    genesis,
 	}
-	if isValidator {
-		cfg.PrivateKy = crypto.GeneratePrivateKey()
+	n, err := node.NewNode(cfg)
+	if err != nil {
+		log.Fatal(err)
 	}
-	n := node.NewNode(cfg)
 	go n.Start(listenAddr, bootstrapNodes)
 
 	return n
@@ -66,8 +121,11 @@ func makeTransaction() {
 		},
 	}
 
-	_, err = c.HandleTransaction(context.TODO(), tx)
+	ack, err := c.HandleTransaction(context.TODO(), tx)
 	if err != nil {
 		log.Fatal(err)
 	}
+	if ack.Reject != proto.RejectReason_ACCEPTED {
+		log.Printf("transaction rejected: %s: %s", ack.Reject, ack.Message)
+	}
 }
@@ -0,0 +1,139 @@
+package node
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/DenisBytes/GoChain/proto"
+)
+
+// Batch collects the changes a block makes to the block, transaction
+// and UTXO stores so they can be applied all at once.
+type Batch struct {
+	blocks []*proto.Block
+	txx    []*proto.Transaction
+	// utxos maps an outpoint key to its new state, nil deletes it.
+	utxos map[string]*UTXO
+	head  string
+}
+
+func NewBatch() *Batch {
+	return &Batch{
+		utxos: make(map[string]*UTXO),
+	}
+}
+
+func (b *Batch) PutBlock(block *proto.Block) {
+	b.blocks = append(b.blocks, block)
+}
+
+func (b *Batch) PutTx(tx *proto.Transaction) {
+	b.txx = append(b.txx, tx)
+}
+
+func (b *Batch) PutUTXO(utxo *UTXO) {
+	b.utxos[fmt.Sprintf("%s_%d", utxo.Hash, utxo.OutIndex)] = utxo
+}
+
+func (b *Batch) DeleteUTXO(key string) {
+	b.utxos[key] = nil
+}
+
+// PutHead sets the tip remembered by block stores implementing HeadStorer.
+func (b *Batch) PutHead(hash string) {
+	b.head = hash
+}
+
+// BatchWriter applies a Batch so that either all or none of its changes
+// are visible, even if the process dies while writing it.
+type BatchWriter interface {
+	WriteBatch(*Batch) error
+}
+
+// storeBatchWriter applies a batch to stores that have no notion of a
+// transaction. Blocks and transactions are written first: they are
+// addressed by their hash, so leftovers of a failed batch are never
+// referenced. The UTXO changes come next and are rolled back if one of
+// them fails, the head is written last. New UTXOs are written before the
+// ones that change, as undoing them only takes a Delete.
+type storeBatchWriter struct {
+	blockStore BlockStorer
+	txStore    TXStorer
+	utxoStore  UTXOStorer
+	// failed is set when a rollback failed and the UTXO store is left
+	// half-applied, every later batch is then refused.
+	failed error
+}
+
+func (w *storeBatchWriter) WriteBatch(b *Batch) error {
+	if w.failed != nil {
+		return w.failed
+	}
+	for _, block := range b.blocks {
+		if err := w.blockStore.Put(block); err != nil {
+			return err
+		}
+	}
+	for _, tx := range b.txx {
+		if err := w.txStore.Put(tx); err != nil {
+			return err
+		}
+	}
+
+	previous := make(map[string]*UTXO, len(b.utxos))
+	keys := make([]string, 0, len(b.utxos))
+	for key := range b.utxos {
+		if utxo, err := w.utxoStore.Get(key); err == nil {
+			prev := *utxo
+			previous[key] = &prev
+		} else {
+			previous[key] = nil
+		}
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if isNew := previous[keys[i]] == nil; isNew != (previous[keys[j]] == nil) {
+			return isNew
+		}
+		return keys[i] < keys[j]
+	})
+	applied := []string{}
+	for _, key := range keys {
+		if err := w.writeUTXO(key, b.utxos[key]); err != nil {
+			return w.rollback(err, applied, previous)
+		}
+		applied = append(applied, key)
+	}
+
+	if hs, ok := w.blockStore.(HeadStorer); ok && b.head != "" {
+		if err := hs.PutHead(b.head); err != nil {
+			return w.rollback(err, applied, previous)
+		}
+	}
+	return nil
+}
+
+func (w *storeBatchWriter) writeUTXO(key string, utxo *UTXO) error {
+	if utxo == nil {
+		return w.utxoStore.Delete(key)
+	}
+	return w.utxoStore.Put(utxo)
+}
+
+// rollback restores the previous state of the keys after err, and
+// returns err. When a key cannot be restored the writer is marked failed
+// and the rollback error is returned along with err.
+func (w *storeBatchWriter) rollback(err error, keys []string, previous map[string]*UTXO) error {
+	var rollbackErrs []error
+	for _, key := range keys {
+		if rerr := w.writeUTXO(key, previous[key]); rerr != nil {
+			rollbackErrs = append(rollbackErrs, fmt.Errorf("utxo %s: %w", key, rerr))
+		}
+	}
+	if len(rollbackErrs) == 0 {
+		return err
+	}
+	w.failed = fmt.Errorf("%w, utxo store left half-applied: %w", ErrRollbackFailed, errors.Join(rollbackErrs...))
+	return errors.Join(err, w.failed)
+}
@@ -3,17 +3,43 @@ package node
 import (
 	"bytes"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"sync"
+	"time"
+
+	pb "google.golang.org/protobuf/proto"
 
 	"github.com/DenisBytes/GoChain/crypto"
+	"github.com/DenisBytes/GoChain/errs"
 	"github.com/DenisBytes/GoChain/proto"
 	"github.com/DenisBytes/GoChain/types"
 )
 
 const (
-	godSeed = "80237c8edc98b244fb36b8940006a585011245ca2214d9fb0100cfb2254e1c7f"
+	defaultCoinbaseMaturity = 10
+
+	// maxTxSize is the size in bytes of the largest transaction accepted.
+	maxTxSize = 100 * 1024
+	// maxBlockSize and maxBlockTxs bound the size in bytes and the number
+	// of transactions of a block.
+	maxBlockSize = 1 << 20
+	maxBlockTxs  = 5000
+	// blockReservedSize is the room left for the header, the signature
+	// and the coinbase when filling a block with transactions.
+	blockReservedSize = 1024
+
+	// blockVersion is the only header version blocks may carry.
+	blockVersion = 1
+	// A block must be timestamped after the median time of the
+	// medianTimeBlocks blocks before it, and no more than
+	// maxFutureBlockTime ahead of the local clock.
+	medianTimeBlocks   = 11
+	maxFutureBlockTime = 2 * time.Minute
 )
 
+var defaultRewardSchedule = HalvingReward{Initial: 50, Interval: 210000}
+
 type HeaderList struct {
 	headers []*proto.Header
 }
@@ -28,6 +54,11 @@ func (list *HeaderList) Add(h *proto.Header) {
 	list.headers = append(list.headers, h)
 }
 
+// Truncate drops every header above the given height.
+func (list *HeaderList) Truncate(height int) {
+	list.headers = list.headers[:height+1]
+}
+
 func (list *HeaderList) Height() int {
 	return list.Len() - 1
 }
@@ -47,72 +78,651 @@ type UTXO struct {
 	Hash     string
 	OutIndex int
 	Amount   int64
-	Spent    bool
+	// Address is the owner of the output, only a key hashing to it can
+	// spend the UTXO.
+	Address []byte
+	Spent   bool
+	// Coinbase is set for outputs of coinbase transactions, which only
+	// mature a number of blocks after Height.
+	Coinbase bool
+	Height   int
+}
+
+// outpointKey returns the key of the UTXO an input spends.
+func outpointKey(input *proto.TxInput) string {
+	return fmt.Sprintf("%s_%d", hex.EncodeToString(input.PrevTxHash), input.PrevOutIndex)
+}
+
+// Output returns the transaction output the UTXO was created from.
+func (u *UTXO) Output() *proto.TxOutput {
+	return &proto.TxOutput{
+		Amount:  u.Amount,
+		Address: u.Address,
+	}
 }
 
 type Chain struct {
+	lock       sync.RWMutex
 	txStore    TXStorer
 	blockStore BlockStorer
 	utxoStore  UTXOStorer
 	headers    *HeaderList
+	blocks     map[string]*BlockNode
+	tip        *BlockNode
+	forkChoice ForkChoice
+	orphans    *orphanBlockPool
+	batches    BatchWriter
+
+	genesis          *Genesis
+	validators       []*crypto.PublicKey
+	rewards          RewardSchedule
+	coinbaseMaturity int
+	supply           int64
+	// failed is set once a batch could not be rolled back, every later
+	// block is then refused.
+	failed error
+
+	// events gathers the changes of the main branch under lock, they are
+	// sent to the listeners holding notifyLock only. notifyLock is always
+	// taken before lock, never the other way round.
+	events     chainEventBuilder
+	notifyLock sync.Mutex
+	listeners  []ChainListener
+}
+
+type ChainOption func(*Chain)
+
+// WithForkChoice sets the rule used to pick between competing branches.
+// The default is MostWork.
+func WithForkChoice(forkChoice ForkChoice) ChainOption {
+	return func(c *Chain) {
+		c.forkChoice = forkChoice
+	}
 }
 
-func NewChain(bs BlockStorer, txStore TXStorer) *Chain {
+// WithBatchWriter sets how the changes of a block are committed to the
+// stores. By default they are written store by store and the UTXO
+// changes are rolled back when a write fails.
+func WithBatchWriter(w BatchWriter) ChainOption {
+	return func(c *Chain) {
+		c.batches = w
+	}
+}
+
+// WithRewardSchedule sets the amount the coinbase transaction of a block
+// may mint. The default halves a reward of 50 every 210000 blocks.
+func WithRewardSchedule(schedule RewardSchedule) ChainOption {
+	return func(c *Chain) {
+		c.rewards = schedule
+	}
+}
+
+// WithCoinbaseMaturity sets how many blocks must be built on top of a
+// coinbase transaction before its outputs can be spent.
+func WithCoinbaseMaturity(blocks int) ChainOption {
+	return func(c *Chain) {
+		c.coinbaseMaturity = blocks
+	}
+}
+
+// WithGenesis starts the chain from the given specification and applies
+// its parameters, options given after it may override them. The default
+// is DevGenesis.
+func WithGenesis(g *Genesis) ChainOption {
+	return func(c *Chain) {
+		c.genesis = g
+		c.rewards = g.Params.BlockReward.Schedule()
+		c.coinbaseMaturity = g.Params.CoinbaseMaturity
+	}
+}
+
+// NewChain creates a chain that starts at the genesis block and keeps
+// its UTXO set in memory.
+func NewChain(bs BlockStorer, txStore TXStorer, opts ...ChainOption) *Chain {
+	chain, err := OpenChain(bs, txStore, NewMemoryUTXOStore(), opts...)
+	if err != nil {
+		panic(err)
+	}
+	return chain
+}
+
+// OpenChain opens the chain kept in the given stores. When the block
+// store remembers a tip (see HeadStorer) the main branch is rebuilt from
+// it, otherwise a new chain is started with the genesis block.
+func OpenChain(bs BlockStorer, txStore TXStorer, utxoStore UTXOStorer, opts ...ChainOption) (*Chain, error) {
 	chain := &Chain{
 		txStore:    txStore,
-		utxoStore:  NewMemoryUTXOStore(),
+		utxoStore:  utxoStore,
 		blockStore: bs,
 		headers:    NewHeadersList(),
+		blocks:     make(map[string]*BlockNode),
+		forkChoice: MostWork,
+		orphans:    newOrphanBlockPool(maxOrphanBlocks, orphanBlockTTL),
+		batches: &storeBatchWriter{
+			blockStore: bs,
+			txStore:    txStore,
+			utxoStore:  utxoStore,
+		},
+		genesis:          DevGenesis(),
+		rewards:          defaultRewardSchedule,
+		coinbaseMaturity: defaultCoinbaseMaturity,
 	}
-	chain.addBlock(createGenesisBlock())
-	return chain
+	for _, opt := range opts {
+		opt(chain)
+	}
+	genesis, err := chain.genesis.Block()
+	if err != nil {
+		return nil, fmt.Errorf("genesis: %w", err)
+	}
+	if chain.validators, err = chain.genesis.validatorKeys(); err != nil {
+		return nil, fmt.Errorf("genesis: %w", err)
+	}
+
+	head := ""
+	if hs, ok := bs.(HeadStorer); ok {
+		var err error
+		if head, err = hs.Head(); err != nil {
+			return nil, err
+		}
+	}
+	if head != "" {
+		return chain, chain.load(head, genesis)
+	}
+
+	node := newBlockNode(hex.EncodeToString(types.HashBlock(genesis)), genesis.Header, nil)
+	if err := chain.connectBlock(node, genesis); err != nil {
+		return nil, err
+	}
+	chain.blocks[node.Hash] = node
+	chain.events.take()
+	return chain, nil
+}
+
+// load rebuilds the main branch by walking back from the head to the
+// genesis block, which must be the given one.
+func (c *Chain) load(head string, genesis *proto.Block) error {
+	blocks := []*proto.Block{}
+	for hash := head; ; {
+		b, err := c.blockStore.Get(hash)
+		if err != nil {
+			return err
+		}
+		blocks = append(blocks, b)
+		if b.Header.Height == 0 {
+			break
+		}
+		hash = hex.EncodeToString(b.Header.PrevHash)
+	}
+
+	if !bytes.Equal(types.HashBlock(blocks[len(blocks)-1]), types.HashBlock(genesis)) {
+		return fmt.Errorf("stored chain has a different genesis block")
+	}
+
+	var parent *BlockNode
+	for i := len(blocks) - 1; i >= 0; i-- {
+		b := blocks[i]
+		node := newBlockNode(hex.EncodeToString(types.HashBlock(b)), b.Header, parent)
+		node.undo = newBlockUndo(b)
+		fees, err := c.blockFees(b)
+		if err != nil {
+			return err
+		}
+		node.undo.fees = fees
+		c.blocks[node.Hash] = node
+		c.headers.Add(b.Header)
+		if err := c.addSupply(b, fees); err != nil {
+			return err
+		}
+		parent = node
+	}
+	c.tip = parent
+	return nil
+}
+
+// TotalSupply returns the amount of coins minted on the main branch by
+// the genesis block and the coinbase transactions.
+func (c *Chain) TotalSupply() int64 {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.supply
+}
+
+// ChainID returns the ID of the network of the chain, which transactions
+// and blocks are signed for.
+func (c *Chain) ChainID() string {
+	return c.genesis.ChainID
+}
+
+// Proposer returns the key of the validator that must sign the block at
+// the given height. The validators of the genesis take turns, round robin
+// by height.
+func (c *Chain) Proposer(height int) *crypto.PublicKey {
+	return c.validators[height%len(c.validators)]
+}
+
+// BlockReward returns the amount a block at the given height may mint.
+func (c *Chain) BlockReward(height int) int64 {
+	return c.rewards.Reward(height)
+}
+
+// blockFees returns the fees paid by the transactions of a stored block.
+// Spent outputs stay in the UTXO store, so their amounts remain known.
+func (c *Chain) blockFees(b *proto.Block) (int64, error) {
+	fees := int64(0)
+	for _, tx := range b.Transactions {
+		if len(tx.Inputs) == 0 || types.IsCoinbase(tx) {
+			continue
+		}
+		for _, input := range tx.Inputs {
+			utxo, err := c.utxoStore.Get(outpointKey(input))
+			if err != nil {
+				return 0, err
+			}
+			if fees, err = types.AddAmounts(fees, utxo.Amount); err != nil {
+				return 0, err
+			}
+		}
+		paid, err := types.SumOutputs(tx)
+		if err != nil {
+			return 0, err
+		}
+		fees -= paid
+	}
+	return fees, nil
+}
+
+// mintedAmount returns the value of the outputs of a block that do not
+// spend anything: the coinbase and the allocations of the genesis block.
+func mintedAmount(b *proto.Block) (int64, error) {
+	minted := int64(0)
+	for _, tx := range b.Transactions {
+		if len(tx.Inputs) > 0 && !types.IsCoinbase(tx) {
+			continue
+		}
+		sum, err := types.SumOutputs(tx)
+		if err != nil {
+			return 0, err
+		}
+		if minted, err = types.AddAmounts(minted, sum); err != nil {
+			return 0, err
+		}
+	}
+	return minted, nil
+}
+
+// supplyAfter returns the supply once b, whose transactions pay the
+// given fees, is connected.
+func (c *Chain) supplyAfter(b *proto.Block, fees int64) (int64, error) {
+	minted, err := mintedAmount(b)
+	if err != nil {
+		return 0, blockErrorf(b, errs.ErrBadCoinbase, "%v", err)
+	}
+	supply, err := types.AddAmounts(c.supply, minted-fees)
+	if err != nil {
+		return 0, blockErrorf(b, errs.ErrBadCoinbase, "supply: %v", err)
+	}
+	return supply, nil
+}
+
+// addSupply adds the coins minted by b to the supply.
+func (c *Chain) addSupply(b *proto.Block, fees int64) error {
+	supply, err := c.supplyAfter(b, fees)
+	if err != nil {
+		return err
+	}
+	c.supply = supply
+	return nil
 }
 
 func (c *Chain) Height() int {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
 	return c.headers.Height()
 }
 
+// Tip returns the last block of the main branch.
+func (c *Chain) Tip() *BlockNode {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.tip
+}
+
+// AddBlock adds a block to the block tree. A block extending the main
+// branch is fully validated and connected right away. A block on a side
+// branch is only checked for a valid signature and stored, unless the
+// fork choice rule prefers its branch, in which case the chain
+// reorganises onto it. A block whose parent is unknown is kept in the
+// orphan pool and ErrOrphanBlock is returned. Orphans waiting for an
+// added block are added right after it.
 func (c *Chain) AddBlock(b *proto.Block) error {
-	if err := c.ValidateBlock(b); err != nil {
+	// The listeners are called outside of the chain lock so they can
+	// query the chain, notifyLock keeps the events in order.
+	c.notifyLock.Lock()
+	defer c.notifyLock.Unlock()
+
+	c.lock.Lock()
+	err := c.addBlockAndOrphans(b)
+	event := c.events.take()
+	c.lock.Unlock()
+
+	if !event.empty() {
+		for _, listener := range c.listeners {
+			listener(event)
+		}
+	}
+	return err
+}
+
+// addBlockAndOrphans adds b and the orphans waiting for it.
+func (c *Chain) addBlockAndOrphans(b *proto.Block) error {
+	hash := hex.EncodeToString(types.HashBlock(b))
+	if err := c.addBlock(hash, b); err != nil {
 		return err
 	}
 
-	return c.addBlock(b)
+	queue := []string{hash}
+	for len(queue) > 0 {
+		parent := queue[0]
+		queue = queue[1:]
+		for _, orphan := range c.orphans.TakeChildren(parent) {
+			hash := hex.EncodeToString(types.HashBlock(orphan))
+			if err := c.addBlock(hash, orphan); err != nil {
+				continue
+			}
+			queue = append(queue, hash)
+		}
+	}
+	return nil
 }
 
-func (c *Chain) addBlock(b *proto.Block) error {
-	c.headers.Add(b.Header)
+// OrphanCount returns the number of blocks waiting for their parent.
+func (c *Chain) OrphanCount() int {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
 
-	for _, tx := range b.Transactions {
-		if err := c.txStore.Put(tx); err != nil {
+	return c.orphans.Len()
+}
+
+func (c *Chain) addBlock(hash string, b *proto.Block) error {
+	if b.Header == nil {
+		return fmt.Errorf("%w: block without header", errs.ErrMalformed)
+	}
+	if c.failed != nil {
+		return c.failed
+	}
+	if _, ok := c.blocks[hash]; ok {
+		return blockErrorf(b, errs.ErrDuplicateBlock, "")
+	}
+	parent, ok := c.blocks[hex.EncodeToString(b.Header.PrevHash)]
+	if !ok {
+		if err := c.checkSigner(b); err != nil {
 			return err
 		}
-		hash := hex.EncodeToString(types.HashTransaction(tx))
-		for i, output := range tx.Outputs {
-			utxo := &UTXO{
-				Hash:     hash,
-				Amount:   output.Amount,
-				OutIndex: i,
-				Spent:    false,
-			}
-			if err := c.utxoStore.Put(utxo); err != nil {
-				return err
+		c.orphans.Add(hash, b)
+		return fmt.Errorf("%w: missing parent [%x]", ErrOrphanBlock, b.Header.PrevHash)
+	}
+	if parent.invalid {
+		return blockErrorf(b, errs.ErrInvalidBranch, "")
+	}
+
+	node := newBlockNode(hash, b.Header, parent)
+	if parent == c.tip {
+		if err := c.validateBlock(b); err != nil {
+			return err
+		}
+		if err := c.connectBlock(node, b); err != nil {
+			return err
+		}
+		c.blocks[hash] = node
+		return nil
+	}
+
+	if err := c.checkBlock(b, parent); err != nil {
+		return err
+	}
+	if err := c.blockStore.Put(b); err != nil {
+		return err
+	}
+	c.blocks[hash] = node
+	if !c.forkChoice(c.tip, node) {
+		return nil
+	}
+	return c.reorganize(node)
+}
+
+// reorganize makes the branch ending at newTip the main branch. The
+// blocks of the current branch above the fork point are disconnected
+// and the blocks of the new branch are validated and connected. If a
+// block of the new branch turns out to be invalid the chain is restored
+// to the branch it was on.
+func (c *Chain) reorganize(newTip *BlockNode) error {
+	var (
+		oldTip = c.tip
+		fork   = findFork(oldTip, newTip)
+	)
+	if err := c.disconnectTo(fork); err != nil {
+		return err
+	}
+	attach := branch(fork, newTip)
+	for i, node := range attach {
+		b, err := c.blockStore.Get(node.Hash)
+		if err == nil {
+			if err = c.validateBlock(b); err != nil {
+				for _, node := range attach[i:] {
+					node.invalid = true
+				}
+			} else {
+				err = c.connectBlock(node, b)
 			}
 		}
-		for _, input := range tx.Inputs {
-			key := fmt.Sprintf("%s_%d", hex.EncodeToString(input.PrevTxHash), input.PrevOutIndex)
-			utxo, err := c.utxoStore.Get(key)
+		if err == nil {
+			continue
+		}
+
+		if err := c.disconnectTo(fork); err != nil {
+			return err
+		}
+		for _, node := range branch(fork, oldTip) {
+			b, err := c.blockStore.Get(node.Hash)
 			if err != nil {
 				return err
 			}
-			utxo.Spent = true
-			if err := c.utxoStore.Put(utxo); err != nil {
+			if err := c.connectBlock(node, b); err != nil {
 				return err
 			}
 		}
+		return fmt.Errorf("reorganisation to block [%s] failed: %w", newTip.Hash, err)
 	}
+	return nil
+}
 
-	return c.blockStore.Put(b)
+// branch returns the blocks after from up to and including to, in
+// ascending order.
+func branch(from, to *BlockNode) []*BlockNode {
+	nodes := []*BlockNode{}
+	for node := to; node != from; node = node.Parent {
+		nodes = append(nodes, node)
+	}
+	for i, j := 0, len(nodes)-1; i < j; i, j = i+1, j-1 {
+		nodes[i], nodes[j] = nodes[j], nodes[i]
+	}
+	return nodes
+}
+
+// disconnectTo disconnects blocks from the tip until fork is the tip.
+func (c *Chain) disconnectTo(fork *BlockNode) error {
+	for c.tip != fork {
+		b, err := c.blockStore.Get(c.tip.Hash)
+		if err != nil {
+			return err
+		}
+		if err := c.disconnectBlock(c.tip, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// blockUndo records the changes a block made to the UTXO set so they
+// can be reverted when the block is disconnected.
+type blockUndo struct {
+	created []string
+	spent   []string
+	// fees is the amount the coinbase claimed from the other
+	// transactions rather than minted.
+	fees int64
+}
+
+func newBlockUndo(b *proto.Block) *blockUndo {
+	undo := &blockUndo{}
+	for _, tx := range b.Transactions {
+		hash := hex.EncodeToString(types.HashTransaction(tx))
+		for i := range tx.Outputs {
+			undo.created = append(undo.created, fmt.Sprintf("%s_%d", hash, i))
+		}
+		if types.IsCoinbase(tx) {
+			continue
+		}
+		for _, input := range tx.Inputs {
+			undo.spent = append(undo.spent, outpointKey(input))
+		}
+	}
+	return undo
+}
+
+// connectBlock applies the block on top of the tip. All the changes of
+// the block are gathered in a batch first, so a failed lookup or write
+// leaves both the stores and the chain untouched.
+func (c *Chain) connectBlock(node *BlockNode, b *proto.Block) error {
+	var (
+		batch = NewBatch()
+		fees  = int64(0)
+	)
+	for _, tx := range b.Transactions {
+		batch.PutTx(tx)
+		fee, err := c.applyTransaction(batch, tx, node.Height)
+		if err != nil {
+			return err
+		}
+		if fees, err = types.AddAmounts(fees, fee); err != nil {
+			return blockErrorf(b, errs.ErrMalformedTx, "fees: %v", err)
+		}
+	}
+	supply, err := c.supplyAfter(b, fees)
+	if err != nil {
+		return err
+	}
+	batch.PutBlock(b)
+	batch.PutHead(node.Hash)
+
+	if err := c.writeBatch(batch); err != nil {
+		return err
+	}
+
+	node.undo = newBlockUndo(b)
+	node.undo.fees = fees
+	c.headers.Add(b.Header)
+	c.tip = node
+	c.supply = supply
+	c.events.connect(node.Hash, b)
+	return nil
+}
+
+// applyTransaction records in batch the UTXOs tx creates and spends, so
+// the transactions after it in the same block see its effects. It
+// returns the fee the transaction pays.
+func (c *Chain) applyTransaction(batch *Batch, tx *proto.Transaction, height int) (int64, error) {
+	var (
+		hash     = hex.EncodeToString(types.HashTransaction(tx))
+		coinbase = types.IsCoinbase(tx)
+	)
+	for i, output := range tx.Outputs {
+		batch.PutUTXO(&UTXO{
+			Hash:     hash,
+			Amount:   output.Amount,
+			Address:  output.Address,
+			OutIndex: i,
+			Spent:    false,
+			Coinbase: coinbase,
+			Height:   height,
+		})
+	}
+	if coinbase || len(tx.Inputs) == 0 {
+		return 0, nil
+	}
+
+	fee := int64(0)
+	for _, input := range tx.Inputs {
+		utxo, err := c.getUTXO(batch, outpointKey(input))
+		if err != nil {
+			return 0, err
+		}
+		utxo.Spent = true
+		batch.PutUTXO(utxo)
+		if fee, err = types.AddAmounts(fee, utxo.Amount); err != nil {
+			return 0, err
+		}
+	}
+	paid, err := types.SumOutputs(tx)
+	if err != nil {
+		return 0, err
+	}
+	return fee - paid, nil
+}
+
+// disconnectBlock reverts the UTXO changes of the tip block and makes
+// its parent the new tip.
+func (c *Chain) disconnectBlock(node *BlockNode, b *proto.Block) error {
+	var (
+		undo  = node.undo
+		batch = NewBatch()
+	)
+	for i := len(undo.spent) - 1; i >= 0; i-- {
+		utxo, err := c.getUTXO(batch, undo.spent[i])
+		if err != nil {
+			return err
+		}
+		utxo.Spent = false
+		batch.PutUTXO(utxo)
+	}
+	for i := len(undo.created) - 1; i >= 0; i-- {
+		batch.DeleteUTXO(undo.created[i])
+	}
+	batch.PutHead(node.Parent.Hash)
+
+	if err := c.writeBatch(batch); err != nil {
+		return err
+	}
+
+	node.undo = nil
+	c.headers.Truncate(node.Height - 1)
+	c.tip = node.Parent
+	// The block was connected, its minted amount is known not to
+	// overflow.
+	minted, _ := mintedAmount(b)
+	c.supply -= minted - undo.fees
+	c.events.disconnect(node.Hash, b)
+	return nil
+}
+
+// getUTXO returns a copy of the UTXO with the given key as it would be
+// after applying batch.
+func (c *Chain) getUTXO(batch *Batch, key string) (*UTXO, error) {
+	utxo, ok := batch.utxos[key]
+	if !ok {
+		var err error
+		if utxo, err = c.utxoStore.Get(key); err != nil {
+			return nil, err
+		}
+	}
+	if utxo == nil {
+		return nil, fmt.Errorf("%w: utxo %s", errs.ErrNotFound, key)
+	}
+	cp := *utxo
+	return &cp, nil
 }
 
 func (c *Chain) GetBlockByHash(hash []byte) (*proto.Block, error) {
@@ -120,9 +730,40 @@ func (c *Chain) GetBlockByHash(hash []byte) (*proto.Block, error) {
 	return c.blockStore.Get(hashHex)
 }
 
+// GetHeaders returns the headers between the heights from and to, both
+// inclusive. The range is clamped to the current height of the chain.
+func (c *Chain) GetHeaders(from, to int) ([]*proto.Header, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if from < 0 || from > to {
+		return nil, fmt.Errorf("%w: header range [%d-%d]", errs.ErrInvalidRequest, from, to)
+	}
+	if to > c.headers.Height() {
+		to = c.headers.Height()
+	}
+	headers := []*proto.Header{}
+	for i := from; i <= to; i++ {
+		headers = append(headers, c.headers.Get(i))
+	}
+	return headers, nil
+}
+
+func (c *Chain) HasBlock(hash []byte) bool {
+	_, err := c.GetBlockByHash(hash)
+	return err == nil
+}
+
 func (c *Chain) GetBlockByHeight(height int) (*proto.Block, error) {
-	if c.Height() < height {
-		return nil, fmt.Errorf("given height [%d] too high - height [%d]", height, c.Height())
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.getBlockByHeight(height)
+}
+
+func (c *Chain) getBlockByHeight(height int) (*proto.Block, error) {
+	if c.headers.Height() < height {
+		return nil, fmt.Errorf("%w: given height [%d] too high - height [%d]", errs.ErrNotFound, height, c.headers.Height())
 	}
 	header := c.headers.Get(height)
 	hash := types.HashHeader(header)
@@ -130,81 +771,299 @@ func (c *Chain) GetBlockByHeight(height int) (*proto.Block, error) {
 }
 
 func (c *Chain) ValidateBlock(b *proto.Block) error {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
 
-	if !types.VerifyBlock(b) {
-		return fmt.Errorf("invalid block signature")
-	}
+	return c.validateBlock(b)
+}
 
-	currentBlock, err := c.GetBlockByHeight(c.Height())
-	if err != nil {
+func (c *Chain) validateBlock(b *proto.Block) error {
+	if b.Header == nil {
+		return fmt.Errorf("%w: block without header", errs.ErrMalformed)
+	}
+	if hex.EncodeToString(b.Header.PrevHash) != c.tip.Hash {
+		return blockErrorf(b, errs.ErrBadPrevHash, "")
+	}
+	if err := c.checkBlock(b, c.tip); err != nil {
 		return err
 	}
-	hash := types.HashBlock(currentBlock)
-	if !bytes.Equal(hash, b.Header.PrevHash) {
-		return fmt.Errorf("invalid previous block hash")
+	// Every transaction is validated against the UTXO set as left by the
+	// transactions before it, so an output cannot be spent twice within
+	// the block while outputs created earlier in the block can be spent.
+	var (
+		height = c.headers.Height() + 1
+		fees   = int64(0)
+		view   = NewBatch()
+	)
+	for i, tx := range b.Transactions {
+		if types.IsCoinbase(tx) {
+			if i != 0 {
+				return blockErrorf(b, errs.ErrBadCoinbase, "coinbase transaction %d is not the first one", i)
+			}
+		} else {
+			fee, err := c.validateTransaction(view, tx)
+			if err != nil {
+				return blockErrorf(b, err, "")
+			}
+			if fees, err = types.AddAmounts(fees, fee); err != nil {
+				return blockErrorf(b, errs.ErrMalformedTx, "fees: %v", err)
+			}
+		}
+		if _, err := c.applyTransaction(view, tx, height); err != nil {
+			return blockErrorf(b, errs.ErrMissingInput, "%v", err)
+		}
 	}
-	for _, tx := range b.Transactions {
-		if err := c.ValidateTransaction(tx); err != nil {
-			return err
+	if len(b.Transactions) > 0 && types.IsCoinbase(b.Transactions[0]) {
+		if err := c.validateCoinbase(b.Transactions[0], height, fees); err != nil {
+			return blockErrorf(b, err, "")
 		}
 	}
 
 	return nil
 }
 
-func (c *Chain) ValidateTransaction(tx *proto.Transaction) error {
-	if !types.VerifyTransaction(tx) {
-		return fmt.Errorf("invalid tx signature")
+// checkBlock runs the checks of b that only depend on the branch it
+// extends: the size limits, the header rules and the signer.
+func (c *Chain) checkBlock(b *proto.Block, parent *BlockNode) error {
+	if n := len(b.Transactions); n > maxBlockTxs {
+		return blockErrorf(b, errs.ErrTooManyTxs, "%d transactions", n)
+	}
+	if size := pb.Size(b); size > maxBlockSize {
+		return blockErrorf(b, errs.ErrBlockTooLarge, "%d bytes", size)
+	}
+	header := b.Header
+	if header.Version != blockVersion {
+		return blockErrorf(b, errs.ErrUnknownVersion, "%d", header.Version)
+	}
+	if height := parent.Height + 1; int(header.Height) != height {
+		return blockErrorf(b, errs.ErrBadHeight, "expected %d", height)
+	}
+	if median := parent.MedianTime(); header.Timestamp <= median {
+		return blockErrorf(b, errs.ErrTimestampTooOld, "%d <= %d", header.Timestamp, median)
 	}
+	if limit := time.Now().Add(maxFutureBlockTime).UnixNano(); header.Timestamp > limit {
+		return blockErrorf(b, errs.ErrTimestampTooNew, "%s", time.Unix(0, header.Timestamp))
+	}
+	if !types.VerifyRootHash(b) {
+		return blockErrorf(b, errs.ErrBadMerkleRoot, "")
+	}
+	return c.checkSigner(b)
+}
 
-	nInputs := len(tx.Inputs)
+// checkSigner checks b is signed by the proposer of its height.
+func (c *Chain) checkSigner(b *proto.Block) error {
+	height := int(b.Header.Height)
+	if height < 1 {
+		return blockErrorf(b, errs.ErrBadHeight, "%d", height)
+	}
+	if !types.VerifyBlock(c.ChainID(), b) {
+		return blockErrorf(b, errs.ErrInvalidSignature, "")
+	}
+	if proposer := c.Proposer(height); !bytes.Equal(b.PublicKey, proposer.Bytes()) {
+		return blockErrorf(b, errs.ErrWrongProposer, "signed by %x instead of %x", b.PublicKey, proposer.Bytes())
+	}
+	return nil
+}
+
+// blockErrorf returns a BlockError for b.
+func blockErrorf(b *proto.Block, err error, format string, args ...any) error {
+	hash := hex.EncodeToString(types.HashBlock(b))
+	return errs.BlockErrorf(hash, int(b.Header.GetHeight()), err, format, args...)
+}
+
+// txErrorf returns a TxError for tx.
+func txErrorf(tx *proto.Transaction, input int, err error, format string, args ...any) error {
 	hash := hex.EncodeToString(types.HashTransaction(tx))
-	sumInputs := 0
-	for i := 0; i < nInputs; i++ {
-		prevHash := hex.EncodeToString(tx.Inputs[i].PrevTxHash)
-		key := fmt.Sprintf("%s_%d", prevHash, i)
-		utxo, err := c.utxoStore.Get(key)
-		sumInputs += int(utxo.Amount)
-		if err != nil {
-			return err
-		}
-		if utxo.Spent {
-			return fmt.Errorf("input %d of tx %s is alredy spent", i, hash)
+	return errs.TxErrorf(hash, input, err, format, args...)
+}
+
+// writeBatch writes batch and marks the chain failed when the batch
+// could not be rolled back.
+func (c *Chain) writeBatch(batch *Batch) error {
+	err := c.batches.WriteBatch(batch)
+	if errors.Is(err, ErrRollbackFailed) {
+		c.failed = err
+	}
+	return err
+}
+
+// validateCoinbase checks that the coinbase of the block at the given
+// height claims no more than the block reward plus the fees paid by the
+// other transactions of the block.
+func (c *Chain) validateCoinbase(tx *proto.Transaction, height int, fees int64) error {
+	if types.CoinbaseHeight(tx) != height {
+		return txErrorf(tx, -1, errs.ErrBadCoinbase, "not committed to height %d", height)
+	}
+	if err := validateOutputs(tx); err != nil {
+		return err
+	}
+	claimed, err := types.SumOutputs(tx)
+	if err != nil {
+		return txErrorf(tx, -1, errs.ErrBadCoinbase, "%v", err)
+	}
+	reward := c.rewards.Reward(height)
+	limit, err := types.AddAmounts(reward, fees)
+	if err != nil {
+		return txErrorf(tx, -1, errs.ErrBadCoinbase, "block reward and fees: %v", err)
+	}
+	if claimed > limit {
+		return txErrorf(tx, -1, errs.ErrBadCoinbase, "claims %d but the block reward is %d and the fees are %d", claimed, reward, fees)
+	}
+	return nil
+}
+
+// validateOutputs rejects outputs that do not carry a positive amount or
+// whose total overflows, which would let a transaction create coins out
+// of thin air.
+func validateOutputs(tx *proto.Transaction) error {
+	for i, output := range tx.Outputs {
+		if output.Amount <= 0 {
+			return txErrorf(tx, -1, errs.ErrMalformedTx, "output %d has a non positive amount %d", i, output.Amount)
 		}
 	}
+	if _, err := types.SumOutputs(tx); err != nil {
+		return txErrorf(tx, -1, errs.ErrMalformedTx, "%v", err)
+	}
+	return nil
+}
 
-	sumOutputs := 0
-	for _, output := range tx.Outputs {
-		sumOutputs += int(output.Amount)
+// checkTransaction runs the checks that do not depend on the state of the
+// chain.
+func checkTransaction(tx *proto.Transaction) error {
+	if types.IsCoinbase(tx) {
+		return txErrorf(tx, -1, errs.ErrMalformedTx, "coinbase transaction outside of a block")
+	}
+	if len(tx.Inputs) == 0 || len(tx.Outputs) == 0 {
+		return txErrorf(tx, -1, errs.ErrMalformedTx, "%d inputs and %d outputs", len(tx.Inputs), len(tx.Outputs))
+	}
+	if size := pb.Size(tx); size > maxTxSize {
+		return txErrorf(tx, -1, errs.ErrTxTooLarge, "%d bytes", size)
+	}
+	if err := validateOutputs(tx); err != nil {
+		return err
 	}
-	if sumInputs < sumOutputs {
-		return fmt.Errorf("insufficient balance got(%d) spending(%d)", sumInputs, sumOutputs)
+	// The signatures commit to the spent outputs, they are verified
+	// once those are known.
+	for i, input := range tx.Inputs {
+		if len(input.PublicKey) != crypto.PubKeyLen || len(input.Signature) != types.InputSignatureLen {
+			return txErrorf(tx, i, errs.ErrInvalidSignature, "not signed")
+		}
 	}
 	return nil
 }
 
-func createGenesisBlock() *proto.Block {
-	privKey := crypto.NewPrivateKeyFromString(godSeed)
+// ValidateTransaction validates tx against the UTXO set. The outputs of
+// the unconfirmed transactions, given parents first, are spendable on
+// top of it, which lets the mempool admit children of its transactions.
+func (c *Chain) ValidateTransaction(tx *proto.Transaction, unconfirmed ...*proto.Transaction) error {
+	_, err := c.TransactionFee(tx, unconfirmed...)
+	return err
+}
 
-	block := &proto.Block{
-		Header: &proto.Header{
-			Version: 1,
-		},
+// ValidateTransactions validates txx in order on top of the tip, as they
+// would be in a block, so a transaction may spend the outputs of the
+// ones before it. It returns the valid transactions, the invalid ones
+// and the fees paid by the valid ones.
+func (c *Chain) ValidateTransactions(txx []*proto.Transaction) ([]*proto.Transaction, []*proto.Transaction, int64) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	var (
+		valid   = []*proto.Transaction{}
+		invalid = []*proto.Transaction{}
+		fees    = int64(0)
+		height  = c.headers.Height() + 1
+		view    = NewBatch()
+	)
+	for _, tx := range txx {
+		fee, err := c.validateTransaction(view, tx)
+		if err != nil {
+			invalid = append(invalid, tx)
+			continue
+		}
+		total, err := types.AddAmounts(fees, fee)
+		if err != nil {
+			invalid = append(invalid, tx)
+			continue
+		}
+		if _, err := c.applyTransaction(view, tx, height); err != nil {
+			invalid = append(invalid, tx)
+			continue
+		}
+		valid = append(valid, tx)
+		fees = total
 	}
-	tx := &proto.Transaction{
-		Version: 1,
-		Inputs:  []*proto.TxInput{},
-		Outputs: []*proto.TxOutput{
-			{
-				Amount:  1000,
-				Address: privKey.Public().Address().Bytes(),
-			},
-		},
+	return valid, invalid, fees
+}
+
+// TransactionFee validates the transaction like ValidateTransaction and
+// returns the fee it pays to the block producer.
+func (c *Chain) TransactionFee(tx *proto.Transaction, unconfirmed ...*proto.Transaction) (int64, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	view := NewBatch()
+	for _, parent := range unconfirmed {
+		if _, err := c.applyTransaction(view, parent, c.headers.Height()+1); err != nil {
+			return 0, txErrorf(tx, -1, errs.ErrMissingInput, "unconfirmed parent: %v", err)
+		}
 	}
+	return c.validateTransaction(view, tx)
+}
 
-	block.Transactions = append(block.Transactions, tx)
+// HasTransaction reports whether the transaction with the given hash was
+// included in a block.
+func (c *Chain) HasTransaction(hash []byte) bool {
+	_, err := c.txStore.Get(hex.EncodeToString(hash))
+	return err == nil
+}
+
+// validateTransaction validates tx against the UTXO set as it would be
+// after applying view.
+func (c *Chain) validateTransaction(view *Batch, tx *proto.Transaction) (int64, error) {
+	if err := checkTransaction(tx); err != nil {
+		return 0, err
+	}
 
-	types.SignBlock(privKey, block)
+	nInputs := len(tx.Inputs)
+	hash := hex.EncodeToString(types.HashTransaction(tx))
+	spent := make([]*proto.TxOutput, nInputs)
+	seen := make(map[string]bool, nInputs)
+	for i := 0; i < nInputs; i++ {
+		key := outpointKey(tx.Inputs[i])
+		if seen[key] {
+			return 0, errs.TxErrorf(hash, i, errs.ErrDoubleSpend, "spends %s twice", key)
+		}
+		seen[key] = true
+		utxo, err := c.getUTXO(view, key)
+		if err != nil {
+			return 0, errs.TxErrorf(hash, i, errs.ErrMissingInput, "%v", err)
+		}
+		if utxo.Spent {
+			return 0, errs.TxErrorf(hash, i, errs.ErrDoubleSpend, "already spent")
+		}
+		if utxo.Coinbase && c.headers.Height()+1-utxo.Height < c.coinbaseMaturity {
+			return 0, errs.TxErrorf(hash, i, errs.ErrImmatureCoinbase, "")
+		}
+		pubKey, err := crypto.PublicKeyFromBytes(tx.Inputs[i].PublicKey)
+		if err != nil {
+			return 0, errs.TxErrorf(hash, i, errs.ErrInvalidSignature, "%v", err)
+		}
+		if !bytes.Equal(pubKey.Address().Bytes(), utxo.Address) {
+			return 0, errs.TxErrorf(hash, i, errs.ErrNotOwner, "")
+		}
+		spent[i] = utxo.Output()
+	}
+	if !types.VerifyTransaction(c.ChainID(), tx, spent) {
+		return 0, errs.TxErrorf(hash, -1, errs.ErrInvalidSignature, "")
+	}
 
-	return block
+	fee, err := types.TransactionFee(tx, spent)
+	if errors.Is(err, types.ErrAmountOverflow) {
+		return 0, errs.TxErrorf(hash, -1, errs.ErrMalformedTx, "%v", err)
+	}
+	if err != nil {
+		return 0, errs.TxErrorf(hash, -1, errs.ErrInsufficientFunds, "%v", err)
+	}
+	return fee, nil
 }
@@ -1,24 +1,45 @@
 package node
 
 import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/DenisBytes/GoChain/crypto"
+	"github.com/DenisBytes/GoChain/errs"
 	"github.com/DenisBytes/GoChain/proto"
 	"github.com/DenisBytes/GoChain/types"
 	"github.com/DenisBytes/GoChain/util"
 )
 
+// godSeed is the seed of the key owning the allocation of DevGenesis.
+const godSeed = "80237c8edc98b244fb36b8940006a585011245ca2214d9fb0100cfb2254e1c7f"
+
+// devChainID is the chain ID of DevGenesis.
+var devChainID = DevGenesis().ChainID
+
+// validatorKey returns the key of the only validator of DevGenesis, the
+// key of godSeed.
+func validatorKey() *crypto.PrivateKey {
+	return crypto.NewPrivateKeyFromString(godSeed)
+}
+
 func randomBlock(t *testing.T, chain *Chain) *proto.Block {
-	privKey := crypto.GeneratePrivateKey()
+	privKey := validatorKey()
 	block := util.RandomBlock()
 	prevBlock, err := chain.GetBlockByHeight(chain.Height())
 	require.Nil(t, err)
 	block.Header.PrevHash = types.HashBlock(prevBlock)
-	types.SignBlock(privKey, block)
+	block.Header.Height = prevBlock.Header.Height + 1
+	types.SignBlock(devChainID, privKey, block)
 
 	return block
 }
@@ -65,7 +86,7 @@ func TestAddBlockWithTx(t *testing.T) {
 	privKey := crypto.NewPrivateKeyFromString(godSeed)
 	receiverAddress := crypto.GeneratePrivateKey().Public().Address().Bytes()
 
-	prevTx, err := chain.txStore.Get("4420225c7f075f1a6210879f0da7e3cd55dd5183a5efae4110dda7dbaea98119")
+	prevTx, err := chain.txStore.Get("fcfd4e1e120df515c882dbd2817cc02ffa7e361be4423405217dd812dc67f440")
 	assert.Nil(t, err)
 
 	inputs := []*proto.TxInput{
@@ -91,11 +112,10 @@ func TestAddBlockWithTx(t *testing.T) {
 		Outputs: outputs,
 	}
 
-	sig := types.SignTransaction(privKey, tx)
-	tx.Inputs[0].Signature = sig.Bytes()
+	require.Nil(t, types.SignTransaction(devChainID, privKey, tx, prevTx.Outputs[:1]))
 
 	block.Transactions = append(block.Transactions, tx)
-	types.SignBlock(privKey, block)
+	types.SignBlock(devChainID, privKey, block)
 	require.Nil(t, chain.AddBlock(block))
 }
 
@@ -105,7 +125,7 @@ func TestAddBlockWithTxInsufficientFunds(t *testing.T) {
 	privKey := crypto.NewPrivateKeyFromString(godSeed)
 	receiverAddress := crypto.GeneratePrivateKey().Public().Address().Bytes()
 
-	prevTx, err := chain.txStore.Get("4420225c7f075f1a6210879f0da7e3cd55dd5183a5efae4110dda7dbaea98119")
+	prevTx, err := chain.txStore.Get("fcfd4e1e120df515c882dbd2817cc02ffa7e361be4423405217dd812dc67f440")
 	assert.Nil(t, err)
 
 	inputs := []*proto.TxInput{
@@ -127,10 +147,834 @@ func TestAddBlockWithTxInsufficientFunds(t *testing.T) {
 		Outputs: outputs,
 	}
 
-	sig := types.SignTransaction(privKey, tx)
-	tx.Inputs[0].Signature = sig.Bytes()
+	require.Nil(t, types.SignTransaction(devChainID, privKey, tx, prevTx.Outputs[:1]))
 
 	block.Transactions = append(block.Transactions, tx)
-	types.SignBlock(privKey, block)
+	types.SignBlock(devChainID, privKey, block)
 	require.NotNil(t, chain.AddBlock(block))
 }
+
+// blockOn builds a block on top of parent signed by validatorKey, the
+// proposer of every height on the dev network.
+func blockOn(parent *proto.Block, txx ...*proto.Transaction) *proto.Block {
+	block := util.RandomBlock()
+	block.Header.PrevHash = types.HashBlock(parent)
+	block.Header.Height = parent.Header.Height + 1
+	block.Transactions = txx
+	types.SignBlock(devChainID, validatorKey(), block)
+	return block
+}
+
+// spendGenesis returns a transaction that moves amount of the genesis
+// output to a fresh address and sends the rest back.
+func spendGenesis(t *testing.T, chain *Chain, amount int64) *proto.Transaction {
+	privKey := crypto.NewPrivateKeyFromString(godSeed)
+	genesis, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+
+	tx := &proto.Transaction{
+		Version: 1,
+		Inputs: []*proto.TxInput{
+			{
+				PublicKey:    privKey.Public().Bytes(),
+				PrevTxHash:   types.HashTransaction(genesis.Transactions[0]),
+				PrevOutIndex: 0,
+			},
+		},
+		Outputs: []*proto.TxOutput{
+			{
+				Amount:  amount,
+				Address: crypto.GeneratePrivateKey().Public().Address().Bytes(),
+			},
+			{
+				Amount:  1000 - amount,
+				Address: privKey.Public().Address().Bytes(),
+			},
+		},
+	}
+	require.Nil(t, types.SignTransaction(chain.ChainID(), privKey, tx, genesis.Transactions[0].Outputs[:1]))
+	return tx
+}
+
+func utxoKey(tx *proto.Transaction, index int) string {
+	return fmt.Sprintf("%s_%d", hex.EncodeToString(types.HashTransaction(tx)), index)
+}
+
+func requireTip(t *testing.T, chain *Chain, block *proto.Block) {
+	tip, err := chain.GetBlockByHeight(chain.Height())
+	require.Nil(t, err)
+	require.Equal(t, types.HashBlock(block), types.HashBlock(tip))
+}
+
+func TestOutputOverflowRejected(t *testing.T) {
+	chain := NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
+	genesis, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+
+	// The outputs wrap around to the 1000 of the genesis output.
+	tx := spendGenesis(t, chain, 100)
+	tx.Outputs = []*proto.TxOutput{
+		{Amount: math.MaxInt64, Address: tx.Outputs[0].Address},
+		{Amount: math.MaxInt64, Address: tx.Outputs[0].Address},
+		{Amount: 1002, Address: tx.Outputs[1].Address},
+	}
+	require.Nil(t, types.SignTransaction(chain.ChainID(), validatorKey(), tx, genesis.Transactions[0].Outputs[:1]))
+
+	require.ErrorIs(t, chain.ValidateTransaction(tx), errs.ErrMalformedTx)
+	require.ErrorIs(t, chain.AddBlock(blockOn(genesis, tx)), errs.ErrMalformedTx)
+	require.Equal(t, 0, chain.Height())
+	require.Equal(t, int64(1000), chain.TotalSupply())
+}
+
+func TestCrossChainReplay(t *testing.T) {
+	// Both networks give the genesis output to the same key.
+	testnet := DevGenesis()
+	testnet.ChainID = "gochain-testnet"
+	chain := NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
+	other := NewChain(NewMemoryBlockStore(), NewMemoryTXStore(), WithGenesis(testnet))
+	genesis, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+	otherGenesis, err := other.GetBlockByHeight(0)
+	require.Nil(t, err)
+	require.Equal(t, types.HashTransaction(genesis.Transactions[0]), types.HashTransaction(otherGenesis.Transactions[0]))
+	require.NotEqual(t, types.HashBlock(genesis), types.HashBlock(otherGenesis))
+
+	// A transaction signed for the testnet spends the same output here,
+	// but its signature does not hold.
+	tx := spendGenesis(t, other, 100)
+	require.Nil(t, other.ValidateTransaction(tx))
+	require.ErrorIs(t, chain.ValidateTransaction(tx), errs.ErrInvalidSignature)
+	require.ErrorIs(t, chain.AddBlock(blockOn(genesis, tx)), errs.ErrInvalidSignature)
+
+	// Neither does a block signed for the testnet.
+	block := blockOn(genesis)
+	types.SignBlock(testnet.ChainID, validatorKey(), block)
+	require.ErrorIs(t, chain.AddBlock(block), errs.ErrInvalidSignature)
+	require.ErrorIs(t, chain.ValidateBlock(block), errs.ErrInvalidSignature)
+	types.SignBlock(chain.ChainID(), validatorKey(), block)
+	require.Nil(t, chain.AddBlock(block))
+}
+
+func TestSpendRequiresOutputOwner(t *testing.T) {
+	chain := NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
+	genesis, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+
+	utxo, err := chain.utxoStore.Get(utxoKey(genesis.Transactions[0], 0))
+	require.Nil(t, err)
+	require.Equal(t, genesis.Transactions[0].Outputs[0].Address, utxo.Address)
+
+	// A thief signing with their own key has a valid signature but does
+	// not own the output.
+	thief := crypto.GeneratePrivateKey()
+	theft := &proto.Transaction{
+		Version: 1,
+		Inputs: []*proto.TxInput{
+			{
+				PublicKey:    thief.Public().Bytes(),
+				PrevTxHash:   types.HashTransaction(genesis.Transactions[0]),
+				PrevOutIndex: 0,
+			},
+		},
+		Outputs: []*proto.TxOutput{
+			{
+				Amount:  1000,
+				Address: thief.Public().Address().Bytes(),
+			},
+		},
+	}
+	spent := genesis.Transactions[0].Outputs[:1]
+	require.Nil(t, types.SignTransaction(devChainID, thief, theft, spent))
+	require.True(t, types.VerifyTransaction(devChainID, theft, spent))
+	require.NotNil(t, chain.ValidateTransaction(theft))
+	require.NotNil(t, chain.AddBlock(blockOn(genesis, theft)))
+	require.Equal(t, 0, chain.Height())
+
+	// The owner can still spend it.
+	tx := spendGenesis(t, chain, 100)
+	require.Nil(t, chain.ValidateTransaction(tx))
+	require.Nil(t, chain.AddBlock(blockOn(genesis, tx)))
+}
+
+func TestDoubleSpendWithinBlock(t *testing.T) {
+	chain := NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
+	genesis, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+	godKey := crypto.NewPrivateKeyFromString(godSeed)
+
+	// Both transactions are valid on their own.
+	first := spendGenesis(t, chain, 100)
+	second := spendGenesis(t, chain, 200)
+	require.Nil(t, chain.ValidateTransaction(first))
+	require.Nil(t, chain.ValidateTransaction(second))
+	require.NotNil(t, chain.ValidateBlock(blockOn(genesis, first, second)))
+	require.NotNil(t, chain.AddBlock(blockOn(genesis, first, second)))
+
+	// A transaction spending the same outpoint through two inputs.
+	twice := &proto.Transaction{
+		Version: 1,
+		Inputs: []*proto.TxInput{
+			{
+				PublicKey:    godKey.Public().Bytes(),
+				PrevTxHash:   types.HashTransaction(genesis.Transactions[0]),
+				PrevOutIndex: 0,
+			},
+			{
+				PublicKey:    godKey.Public().Bytes(),
+				PrevTxHash:   types.HashTransaction(genesis.Transactions[0]),
+				PrevOutIndex: 0,
+			},
+		},
+		Outputs: []*proto.TxOutput{
+			{
+				Amount:  2000,
+				Address: godKey.Public().Address().Bytes(),
+			},
+		},
+	}
+	genesisOutput := genesis.Transactions[0].Outputs[0]
+	require.Nil(t, types.SignTransaction(devChainID, godKey, twice, []*proto.TxOutput{genesisOutput, genesisOutput}))
+	require.NotNil(t, chain.ValidateTransaction(twice))
+	require.NotNil(t, chain.AddBlock(blockOn(genesis, twice)))
+	require.Equal(t, 0, chain.Height())
+
+	// A child spending the change of its parent in the same block is
+	// valid, but only after the parent.
+	child := &proto.Transaction{
+		Version: 1,
+		Inputs: []*proto.TxInput{
+			{
+				PublicKey:    godKey.Public().Bytes(),
+				PrevTxHash:   types.HashTransaction(first),
+				PrevOutIndex: 1,
+			},
+		},
+		Outputs: []*proto.TxOutput{
+			{
+				Amount:  900,
+				Address: crypto.GeneratePrivateKey().Public().Address().Bytes(),
+			},
+		},
+	}
+	require.Nil(t, types.SignTransaction(devChainID, godKey, child, first.Outputs[1:]))
+	require.NotNil(t, chain.ValidateTransaction(child))
+	require.NotNil(t, chain.AddBlock(blockOn(genesis, child, first)))
+	require.NotNil(t, chain.AddBlock(blockOn(genesis, first, child, second)))
+
+	b1 := blockOn(genesis, first, child)
+	require.Nil(t, chain.AddBlock(b1))
+	requireTip(t, chain, b1)
+	utxo, err := chain.utxoStore.Get(utxoKey(first, 1))
+	require.Nil(t, err)
+	require.True(t, utxo.Spent)
+	utxo, err = chain.utxoStore.Get(utxoKey(child, 0))
+	require.Nil(t, err)
+	require.False(t, utxo.Spent)
+}
+
+func TestForkSwitchesToHeavierBranch(t *testing.T) {
+	chain := NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
+	genesis, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+	genesisUTXO := utxoKey(genesis.Transactions[0], 0)
+
+	tx := spendGenesis(t, chain, 100)
+	a1 := blockOn(genesis, tx)
+	a2 := blockOn(a1)
+	require.Nil(t, chain.AddBlock(a1))
+	require.Nil(t, chain.AddBlock(a2))
+
+	b1 := blockOn(genesis)
+	b2 := blockOn(b1)
+	require.Nil(t, chain.AddBlock(b1))
+	require.Nil(t, chain.AddBlock(b2))
+
+	// Equal work, the branch seen first stays.
+	require.Equal(t, 2, chain.Height())
+	requireTip(t, chain, a2)
+
+	b3 := blockOn(b2)
+	require.Nil(t, chain.AddBlock(b3))
+	require.Equal(t, 3, chain.Height())
+	requireTip(t, chain, b3)
+	mainBlock, err := chain.GetBlockByHeight(1)
+	require.Nil(t, err)
+	require.Equal(t, types.HashBlock(b1), types.HashBlock(mainBlock))
+
+	// The UTXO changes of a1 are reverted.
+	utxo, err := chain.utxoStore.Get(genesisUTXO)
+	require.Nil(t, err)
+	require.False(t, utxo.Spent)
+	_, err = chain.utxoStore.Get(utxoKey(tx, 0))
+	require.NotNil(t, err)
+
+	// So the transaction can be mined again on the new branch.
+	b4 := blockOn(b3, tx)
+	require.Nil(t, chain.AddBlock(b4))
+	requireTip(t, chain, b4)
+	utxo, err = chain.utxoStore.Get(genesisUTXO)
+	require.Nil(t, err)
+	require.True(t, utxo.Spent)
+}
+
+func TestForkWithLongestChain(t *testing.T) {
+	chain := NewChain(NewMemoryBlockStore(), NewMemoryTXStore(), WithForkChoice(LongestChain))
+	genesis, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+
+	a1 := blockOn(genesis)
+	require.Nil(t, chain.AddBlock(a1))
+	b1 := blockOn(genesis)
+	require.Nil(t, chain.AddBlock(b1))
+	requireTip(t, chain, a1)
+
+	b2 := blockOn(b1)
+	require.Nil(t, chain.AddBlock(b2))
+	requireTip(t, chain, b2)
+
+	// Switching back and forth.
+	a2 := blockOn(a1)
+	a3 := blockOn(a2)
+	require.Nil(t, chain.AddBlock(a2))
+	requireTip(t, chain, b2)
+	require.Nil(t, chain.AddBlock(a3))
+	requireTip(t, chain, a3)
+	require.Equal(t, 3, chain.Height())
+}
+
+func TestReorgToInvalidBranch(t *testing.T) {
+	chain := NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
+	genesis, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+
+	privKey := crypto.GeneratePrivateKey()
+	tx := spendGenesis(t, chain, 100)
+	tx.Outputs[0].Address = privKey.Public().Address().Bytes()
+	require.Nil(t, types.SignTransaction(devChainID, crypto.NewPrivateKeyFromString(godSeed), tx, genesis.Transactions[0].Outputs[:1]))
+	a1 := blockOn(genesis, tx)
+	a2 := blockOn(a1)
+	require.Nil(t, chain.AddBlock(a1))
+	require.Nil(t, chain.AddBlock(a2))
+
+	// b1 spends an output that only exists on the main branch, which
+	// is only noticed once the branch is connected.
+	spendA1 := &proto.Transaction{
+		Version: 1,
+		Inputs: []*proto.TxInput{
+			{
+				PublicKey:    privKey.Public().Bytes(),
+				PrevTxHash:   types.HashTransaction(tx),
+				PrevOutIndex: 0,
+			},
+		},
+		Outputs: []*proto.TxOutput{
+			{
+				Amount:  100,
+				Address: privKey.Public().Address().Bytes(),
+			},
+		},
+	}
+	require.Nil(t, types.SignTransaction(devChainID, privKey, spendA1, tx.Outputs[:1]))
+	require.Nil(t, chain.ValidateTransaction(spendA1))
+	b1 := blockOn(genesis, spendA1)
+	b2 := blockOn(b1)
+	b3 := blockOn(b2)
+	require.Nil(t, chain.AddBlock(b1))
+	require.Nil(t, chain.AddBlock(b2))
+	require.NotNil(t, chain.AddBlock(b3))
+
+	require.Equal(t, 2, chain.Height())
+	requireTip(t, chain, a2)
+	_, err = chain.utxoStore.Get(utxoKey(tx, 0))
+	require.Nil(t, err)
+
+	require.NotNil(t, chain.AddBlock(blockOn(b3)))
+	requireTip(t, chain, a2)
+}
+
+func TestHighestFinalizedRefusesDeepReorg(t *testing.T) {
+	chain := NewChain(NewMemoryBlockStore(), NewMemoryTXStore(), WithForkChoice(HighestFinalized(2, LongestChain)))
+	genesis, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+
+	main := []*proto.Block{genesis}
+	for i := 1; i <= 4; i++ {
+		main = append(main, blockOn(main[i-1]))
+		require.Nil(t, chain.AddBlock(main[i]))
+	}
+
+	// Forking off genesis would revert finalized blocks.
+	parent := genesis
+	for i := 1; i <= 6; i++ {
+		parent = blockOn(parent)
+		require.Nil(t, chain.AddBlock(parent))
+	}
+	requireTip(t, chain, main[4])
+
+	// Forking off height 2 only reverts blocks that are not final yet.
+	parent = main[2]
+	for i := 3; i <= 5; i++ {
+		parent = blockOn(parent)
+		require.Nil(t, chain.AddBlock(parent))
+	}
+	requireTip(t, chain, parent)
+	require.Equal(t, 5, chain.Height())
+}
+
+func TestOpenChainFromDisk(t *testing.T) {
+	dir := t.TempDir()
+	db, err := OpenDB(dir)
+	require.Nil(t, err)
+	chain, err := openDiskChain(db)
+	require.Nil(t, err)
+
+	genesis, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+	tx := spendGenesis(t, chain, 100)
+	b1 := blockOn(genesis, tx)
+	b2 := blockOn(b1)
+	require.Nil(t, chain.AddBlock(b1))
+	require.Nil(t, chain.AddBlock(b2))
+	require.Nil(t, db.Close())
+
+	db, err = OpenDB(dir)
+	require.Nil(t, err)
+	defer db.Close()
+	chain, err = openDiskChain(db)
+	require.Nil(t, err)
+	require.Equal(t, 2, chain.Height())
+	requireTip(t, chain, b2)
+
+	utxo, err := chain.utxoStore.Get(utxoKey(genesis.Transactions[0], 0))
+	require.Nil(t, err)
+	require.True(t, utxo.Spent)
+	utxo, err = chain.utxoStore.Get(utxoKey(tx, 0))
+	require.Nil(t, err)
+	require.Equal(t, int64(100), utxo.Amount)
+
+	// The reopened chain keeps growing and can still reorganise.
+	b3 := blockOn(b2)
+	require.Nil(t, chain.AddBlock(b3))
+	parent := genesis
+	for i := 0; i < 4; i++ {
+		parent = blockOn(parent)
+		require.Nil(t, chain.AddBlock(parent))
+	}
+	requireTip(t, chain, parent)
+	utxo, err = chain.utxoStore.Get(utxoKey(genesis.Transactions[0], 0))
+	require.Nil(t, err)
+	require.False(t, utxo.Spent)
+}
+
+func openDiskChain(db *DB) (*Chain, error) {
+	return OpenChain(NewDiskBlockStore(db), NewDiskTXStore(db), NewDiskUTXOStore(db), WithBatchWriter(db))
+}
+
+var errDiskFull = errors.New("disk full")
+
+// failingUTXOStore fails every Put once failAfter puts succeeded.
+type failingUTXOStore struct {
+	*MemoryUTXOStore
+	puts      int
+	failAfter int
+}
+
+func (s *failingUTXOStore) Put(utxo *UTXO) error {
+	if s.failAfter >= 0 && s.puts >= s.failAfter {
+		return errDiskFull
+	}
+	s.puts++
+	return s.MemoryUTXOStore.Put(utxo)
+}
+
+func TestAddBlockWriteFailureLeavesChainUntouched(t *testing.T) {
+	utxoStore := &failingUTXOStore{MemoryUTXOStore: NewMemoryUTXOStore(), failAfter: -1}
+	chain, err := OpenChain(NewMemoryBlockStore(), NewMemoryTXStore(), utxoStore)
+	require.Nil(t, err)
+	genesis, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+	genesisUTXO := utxoKey(genesis.Transactions[0], 0)
+
+	tx := spendGenesis(t, chain, 100)
+	block := blockOn(genesis, tx)
+
+	// The block creates two outputs and spends one, every put fails
+	// after the first two.
+	utxoStore.puts = 0
+	utxoStore.failAfter = 2
+	require.ErrorIs(t, chain.AddBlock(block), errDiskFull)
+
+	require.Equal(t, 0, chain.Height())
+	requireTip(t, chain, genesis)
+	utxo, err := chain.utxoStore.Get(genesisUTXO)
+	require.Nil(t, err)
+	require.False(t, utxo.Spent)
+	for i := range tx.Outputs {
+		_, err := chain.utxoStore.Get(utxoKey(tx, i))
+		require.NotNil(t, err)
+	}
+
+	// Once the store works again the same block goes in.
+	utxoStore.failAfter = -1
+	require.Nil(t, chain.AddBlock(block))
+	requireTip(t, chain, block)
+	utxo, err = chain.utxoStore.Get(genesisUTXO)
+	require.Nil(t, err)
+	require.True(t, utxo.Spent)
+}
+
+func TestAddBlockFailedRollbackStopsChain(t *testing.T) {
+	g := DevGenesis()
+	g.Allocations = append(g.Allocations, g.Allocations[0])
+	utxoStore := &failingUTXOStore{MemoryUTXOStore: NewMemoryUTXOStore(), failAfter: -1}
+	chain, err := OpenChain(NewMemoryBlockStore(), NewMemoryTXStore(), utxoStore, WithGenesis(g))
+	require.Nil(t, err)
+	genesis, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+
+	// Spend both genesis outputs: the second spend fails, and so does
+	// restoring the first one.
+	privKey := crypto.NewPrivateKeyFromString(godSeed)
+	tx := &proto.Transaction{
+		Version: 1,
+		Outputs: []*proto.TxOutput{{Amount: 2000, Address: privKey.Public().Address().Bytes()}},
+	}
+	for i := range genesis.Transactions[0].Outputs {
+		tx.Inputs = append(tx.Inputs, &proto.TxInput{
+			PublicKey:    privKey.Public().Bytes(),
+			PrevTxHash:   types.HashTransaction(genesis.Transactions[0]),
+			PrevOutIndex: uint32(i),
+		})
+	}
+	require.Nil(t, types.SignTransaction(devChainID, privKey, tx, genesis.Transactions[0].Outputs))
+	block := blockOn(genesis, tx)
+
+	utxoStore.puts = 0
+	utxoStore.failAfter = 2
+	err = chain.AddBlock(block)
+	require.ErrorIs(t, err, errDiskFull)
+	require.ErrorIs(t, err, ErrRollbackFailed)
+	requireTip(t, chain, genesis)
+
+	// The store is half-applied, nothing goes in any more.
+	utxoStore.failAfter = -1
+	require.ErrorIs(t, chain.AddBlock(block), ErrRollbackFailed)
+	requireTip(t, chain, genesis)
+}
+
+func TestConnectBlockMissingInputLeavesChainUntouched(t *testing.T) {
+	chain := NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
+	genesis, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+
+	var (
+		tx      = spendGenesis(t, chain, 100)
+		privKey = crypto.GeneratePrivateKey()
+		missing = &proto.Transaction{
+			Version: 1,
+			Inputs: []*proto.TxInput{
+				{
+					PublicKey:    privKey.Public().Bytes(),
+					PrevTxHash:   util.RandomHash(),
+					PrevOutIndex: 0,
+				},
+			},
+		}
+	)
+	block := blockOn(genesis, tx, missing)
+	hash := hex.EncodeToString(types.HashBlock(block))
+
+	chain.lock.Lock()
+	err = chain.connectBlock(newBlockNode(hash, block.Header, chain.tip), block)
+	chain.lock.Unlock()
+	require.NotNil(t, err)
+
+	require.Equal(t, 0, chain.Height())
+	require.False(t, chain.HasBlock(types.HashBlock(block)))
+	_, err = chain.txStore.Get(hex.EncodeToString(types.HashTransaction(tx)))
+	require.NotNil(t, err)
+	utxo, err := chain.utxoStore.Get(utxoKey(genesis.Transactions[0], 0))
+	require.Nil(t, err)
+	require.False(t, utxo.Spent)
+}
+
+func TestDiskChainRecoversFromTornBlockWrite(t *testing.T) {
+	dir := t.TempDir()
+	db, err := OpenDB(dir)
+	require.Nil(t, err)
+	chain, err := openDiskChain(db)
+	require.Nil(t, err)
+	genesis, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+
+	tx := spendGenesis(t, chain, 100)
+	block := blockOn(genesis, tx)
+	before := db.activeSize
+	require.Nil(t, chain.AddBlock(block))
+	after := db.activeSize
+	require.Nil(t, db.Close())
+
+	// Simulate a crash halfway through writing the block.
+	require.Nil(t, os.Truncate(filepath.Join(dir, "segment-000000.log"), before+(after-before)/2))
+
+	db, err = OpenDB(dir)
+	require.Nil(t, err)
+	defer db.Close()
+	chain, err = openDiskChain(db)
+	require.Nil(t, err)
+	require.Equal(t, 0, chain.Height())
+	require.False(t, chain.HasBlock(types.HashBlock(block)))
+	utxo, err := chain.utxoStore.Get(utxoKey(genesis.Transactions[0], 0))
+	require.Nil(t, err)
+	require.False(t, utxo.Spent)
+	_, err = chain.utxoStore.Get(utxoKey(tx, 0))
+	require.NotNil(t, err)
+
+	require.Nil(t, chain.AddBlock(block))
+	requireTip(t, chain, block)
+}
+
+func coinbaseTo(height int, amount int64, privKey *crypto.PrivateKey) *proto.Transaction {
+	return types.NewCoinbaseTransaction(height, &proto.TxOutput{
+		Amount:  amount,
+		Address: privKey.Public().Address().Bytes(),
+	})
+}
+
+func TestCoinbaseReward(t *testing.T) {
+	chain := NewChain(NewMemoryBlockStore(), NewMemoryTXStore(), WithRewardSchedule(FixedReward(50)))
+	genesis, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+	require.Equal(t, int64(1000), chain.TotalSupply())
+	privKey := crypto.GeneratePrivateKey()
+
+	require.NotNil(t, chain.AddBlock(blockOn(genesis, coinbaseTo(1, 51, privKey))))
+	require.NotNil(t, chain.AddBlock(blockOn(genesis, coinbaseTo(2, 50, privKey))))
+	require.NotNil(t, chain.AddBlock(blockOn(genesis, coinbaseTo(1, -10, privKey), coinbaseTo(1, 60, privKey))))
+	require.NotNil(t, chain.AddBlock(blockOn(genesis, spendGenesis(t, chain, 10), coinbaseTo(1, 50, privKey))))
+	require.NotNil(t, chain.AddBlock(blockOn(genesis, coinbaseTo(1, 25, privKey), coinbaseTo(1, 25, privKey))))
+	require.Equal(t, 0, chain.Height())
+
+	// Outputs wrapping around to the reward do not pass either.
+	overflow := types.NewCoinbaseTransaction(1,
+		&proto.TxOutput{Amount: math.MaxInt64, Address: privKey.Public().Address().Bytes()},
+		&proto.TxOutput{Amount: math.MaxInt64, Address: privKey.Public().Address().Bytes()},
+		&proto.TxOutput{Amount: 52, Address: privKey.Public().Address().Bytes()},
+	)
+	require.ErrorIs(t, chain.AddBlock(blockOn(genesis, overflow)), errs.ErrMalformedTx)
+	require.Equal(t, int64(1000), chain.TotalSupply())
+
+	b1 := blockOn(genesis, coinbaseTo(1, 50, privKey), spendGenesis(t, chain, 10))
+	require.Nil(t, chain.AddBlock(b1))
+	require.Equal(t, int64(1050), chain.TotalSupply())
+
+	// Claiming less than the reward is allowed.
+	b2 := blockOn(b1, coinbaseTo(2, 20, privKey))
+	require.Nil(t, chain.AddBlock(b2))
+	require.Equal(t, int64(1070), chain.TotalSupply())
+
+	// Blocks without a coinbase mint nothing, a reorg onto them takes
+	// the minted coins out of the supply again.
+	parent := genesis
+	for i := 0; i < 3; i++ {
+		parent = blockOn(parent)
+		require.Nil(t, chain.AddBlock(parent))
+	}
+	requireTip(t, chain, parent)
+	require.Equal(t, int64(1000), chain.TotalSupply())
+}
+
+func TestCoinbaseClaimsFees(t *testing.T) {
+	chain := NewChain(NewMemoryBlockStore(), NewMemoryTXStore(), WithRewardSchedule(FixedReward(50)))
+	genesis, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+	privKey := crypto.GeneratePrivateKey()
+
+	// Send back 30 less than the genesis output, leaving it as the fee.
+	tx := spendGenesis(t, chain, 10)
+	tx.Outputs[1].Amount -= 30
+	require.Nil(t, types.SignTransaction(devChainID, crypto.NewPrivateKeyFromString(godSeed), tx, genesis.Transactions[0].Outputs[:1]))
+
+	fee, err := chain.TransactionFee(tx)
+	require.Nil(t, err)
+	require.Equal(t, int64(30), fee)
+
+	require.NotNil(t, chain.AddBlock(blockOn(genesis, coinbaseTo(1, 81, privKey), tx)))
+	require.NotNil(t, chain.AddBlock(blockOn(genesis, coinbaseTo(1, 80, privKey))))
+	require.Equal(t, 0, chain.Height())
+
+	require.Nil(t, chain.AddBlock(blockOn(genesis, coinbaseTo(1, 80, privKey), tx)))
+	require.Equal(t, 1, chain.Height())
+	// The fee moves existing coins, only the reward adds to the supply.
+	require.Equal(t, int64(1050), chain.TotalSupply())
+}
+
+func TestCoinbaseMaturity(t *testing.T) {
+	chain := NewChain(NewMemoryBlockStore(), NewMemoryTXStore(), WithCoinbaseMaturity(2))
+	genesis, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+	privKey := crypto.GeneratePrivateKey()
+
+	coinbase := coinbaseTo(1, chain.BlockReward(1), privKey)
+	b1 := blockOn(genesis, coinbase)
+	require.Nil(t, chain.AddBlock(b1))
+
+	tx := &proto.Transaction{
+		Version: 1,
+		Inputs: []*proto.TxInput{
+			{
+				PublicKey:    privKey.Public().Bytes(),
+				PrevTxHash:   types.HashTransaction(coinbase),
+				PrevOutIndex: 0,
+			},
+		},
+		Outputs: []*proto.TxOutput{
+			{
+				Amount:  chain.BlockReward(1),
+				Address: crypto.GeneratePrivateKey().Public().Address().Bytes(),
+			},
+		},
+	}
+	require.Nil(t, types.SignTransaction(devChainID, privKey, tx, coinbase.Outputs))
+
+	require.NotNil(t, chain.ValidateTransaction(tx))
+	require.NotNil(t, chain.AddBlock(blockOn(b1, tx)))
+
+	b2 := blockOn(b1)
+	require.Nil(t, chain.AddBlock(b2))
+	require.Nil(t, chain.ValidateTransaction(tx))
+	require.Nil(t, chain.AddBlock(blockOn(b2, tx)))
+}
+
+func TestNegativeOutputsAreRejected(t *testing.T) {
+	chain := NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
+	tx := spendGenesis(t, chain, 2000)
+	require.Equal(t, int64(-1000), tx.Outputs[1].Amount)
+	require.NotNil(t, chain.ValidateTransaction(tx))
+}
+
+// resign signs block again after its header was changed.
+func resign(block *proto.Block) *proto.Block {
+	types.SignBlock(devChainID, validatorKey(), block)
+	return block
+}
+
+func TestBlockHeaderRules(t *testing.T) {
+	chain := NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
+	genesis, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+
+	b := blockOn(genesis)
+	b.Header.Height = 2
+	require.ErrorIs(t, chain.ValidateBlock(resign(b)), errs.ErrBadHeight)
+	require.ErrorIs(t, chain.AddBlock(b), errs.ErrBadHeight)
+
+	b = blockOn(genesis)
+	b.Header.Version = 2
+	require.ErrorIs(t, chain.AddBlock(resign(b)), errs.ErrUnknownVersion)
+
+	b = blockOn(genesis)
+	b.Header.Timestamp = time.Now().Add(time.Hour).UnixNano()
+	require.ErrorIs(t, chain.AddBlock(resign(b)), errs.ErrTimestampTooNew)
+
+	b = blockOn(genesis)
+	b.Header.Timestamp = genesis.Header.Timestamp
+	require.ErrorIs(t, chain.AddBlock(resign(b)), errs.ErrTimestampTooOld)
+
+	// The timestamps of the genesis block and the three blocks after it
+	// are 0, 100, 200 and 300, so the median time is 200.
+	parent := genesis
+	for i := 1; i <= 3; i++ {
+		b := blockOn(parent)
+		b.Header.Timestamp = int64(i * 100)
+		require.Nil(t, chain.AddBlock(resign(b)))
+		parent = b
+	}
+	b = blockOn(parent)
+	b.Header.Timestamp = 200
+	require.ErrorIs(t, chain.AddBlock(resign(b)), errs.ErrTimestampTooOld)
+	b.Header.Timestamp = 201
+	require.Nil(t, chain.AddBlock(resign(b)))
+	require.Equal(t, 4, chain.Height())
+
+	// Blocks on side branches follow the same rules.
+	b = blockOn(genesis)
+	b.Header.Height = 3
+	require.ErrorIs(t, chain.AddBlock(resign(b)), errs.ErrBadHeight)
+	require.False(t, chain.HasBlock(types.HashBlock(b)))
+}
+
+func TestBlockSizeLimits(t *testing.T) {
+	chain := NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
+	genesis, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+
+	txx := make([]*proto.Transaction, maxBlockTxs+1)
+	for i := range txx {
+		txx[i] = &proto.Transaction{Version: 1}
+	}
+	require.ErrorIs(t, chain.AddBlock(blockOn(genesis, txx...)), errs.ErrTooManyTxs)
+
+	large := &proto.Transaction{
+		Version: 1,
+		Outputs: []*proto.TxOutput{{Amount: 1, Address: make([]byte, maxBlockSize)}},
+	}
+	require.ErrorIs(t, chain.AddBlock(blockOn(genesis, large)), errs.ErrBlockTooLarge)
+	require.Equal(t, 0, chain.Height())
+}
+
+// validatorGenesis returns DevGenesis with count new validators instead
+// of its own, and their keys.
+func validatorGenesis(count int) (*Genesis, []*crypto.PrivateKey) {
+	g := DevGenesis()
+	g.Validators = nil
+	keys := make([]*crypto.PrivateKey, count)
+	for i := range keys {
+		keys[i] = crypto.GeneratePrivateKey()
+		g.Validators = append(g.Validators, hex.EncodeToString(keys[i].Public().Bytes()))
+	}
+	return g, keys
+}
+
+func TestBlocksSignedByProposer(t *testing.T) {
+	g, keys := validatorGenesis(3)
+	chain := NewChain(NewMemoryBlockStore(), NewMemoryTXStore(), WithGenesis(g))
+	for height, want := range []int{0, 1, 2, 0, 1} {
+		require.Equal(t, keys[want].Public().Bytes(), chain.Proposer(height).Bytes())
+	}
+
+	parent, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+	for height := 1; height <= 4; height++ {
+		block := blockOn(parent)
+		for i, key := range keys {
+			if i == height%len(keys) {
+				continue
+			}
+			types.SignBlock(chain.ChainID(), key, block)
+			require.ErrorIs(t, chain.ValidateBlock(block), errs.ErrWrongProposer)
+			require.ErrorIs(t, chain.AddBlock(block), errs.ErrWrongProposer)
+		}
+		types.SignBlock(chain.ChainID(), keys[height%len(keys)], block)
+		require.Nil(t, chain.ValidateBlock(block))
+		require.Nil(t, chain.AddBlock(block))
+		parent = block
+	}
+	require.Equal(t, 4, chain.Height())
+
+	// Orphans and side branches are checked too.
+	orphan := blockOn(blockOn(parent))
+	types.SignBlock(chain.ChainID(), keys[1], orphan)
+	require.ErrorIs(t, chain.AddBlock(orphan), errs.ErrWrongProposer)
+	require.Equal(t, 0, chain.OrphanCount())
+
+	genesis, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+	fork := blockOn(genesis)
+	fork.Header.Timestamp++
+	types.SignBlock(chain.ChainID(), keys[0], fork)
+	require.ErrorIs(t, chain.AddBlock(fork), errs.ErrWrongProposer)
+	types.SignBlock(chain.ChainID(), keys[1], fork)
+	require.Nil(t, chain.AddBlock(fork))
+	require.Equal(t, 4, chain.Height())
+}
@@ -0,0 +1,206 @@
+package node
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	pb "google.golang.org/protobuf/proto"
+
+	"github.com/DenisBytes/GoChain/errs"
+	"github.com/DenisBytes/GoChain/proto"
+	"github.com/DenisBytes/GoChain/types"
+)
+
+const (
+	blockKeyPrefix = "block/"
+	txKeyPrefix    = "tx/"
+	utxoKeyPrefix  = "utxo/"
+	headKey        = "head"
+)
+
+// HeadStorer is implemented by block stores that remember the tip of the
+// main branch, which lets a chain be reopened where it was left.
+type HeadStorer interface {
+	PutHead(string) error
+	Head() (string, error)
+}
+
+type DiskBlockStore struct {
+	db *DB
+}
+
+func NewDiskBlockStore(db *DB) *DiskBlockStore {
+	return &DiskBlockStore{
+		db: db,
+	}
+}
+
+func (s *DiskBlockStore) Get(hash string) (*proto.Block, error) {
+	b, err := s.db.Get(blockKeyPrefix + hash)
+	if errors.Is(err, errs.ErrNotFound) {
+		return nil, fmt.Errorf("%w: block [%s]", errs.ErrNotFound, hash)
+	}
+	if err != nil {
+		return nil, err
+	}
+	block := &proto.Block{}
+	if err := pb.Unmarshal(b, block); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+func (s *DiskBlockStore) Put(b *proto.Block) error {
+	op, err := blockOp(b)
+	if err != nil {
+		return err
+	}
+	return s.db.write([]dbOp{op})
+}
+
+func (s *DiskBlockStore) PutHead(hash string) error {
+	return s.db.Put(headKey, []byte(hash))
+}
+
+// Head returns the hash of the tip, or an empty string for a new store.
+func (s *DiskBlockStore) Head() (string, error) {
+	b, err := s.db.Get(headKey)
+	if errors.Is(err, errs.ErrNotFound) {
+		return "", nil
+	}
+	return string(b), err
+}
+
+type DiskTXStore struct {
+	db *DB
+}
+
+func NewDiskTXStore(db *DB) *DiskTXStore {
+	return &DiskTXStore{
+		db: db,
+	}
+}
+
+func (s *DiskTXStore) Get(hash string) (*proto.Transaction, error) {
+	b, err := s.db.Get(txKeyPrefix + hash)
+	if errors.Is(err, errs.ErrNotFound) {
+		return nil, fmt.Errorf("%w: tx %s", errs.ErrNotFound, hash)
+	}
+	if err != nil {
+		return nil, err
+	}
+	tx := &proto.Transaction{}
+	if err := pb.Unmarshal(b, tx); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+func (s *DiskTXStore) Put(tx *proto.Transaction) error {
+	op, err := txOp(tx)
+	if err != nil {
+		return err
+	}
+	return s.db.write([]dbOp{op})
+}
+
+type DiskUTXOStore struct {
+	db *DB
+}
+
+func NewDiskUTXOStore(db *DB) *DiskUTXOStore {
+	return &DiskUTXOStore{
+		db: db,
+	}
+}
+
+func (s *DiskUTXOStore) Get(hash string) (*UTXO, error) {
+	b, err := s.db.Get(utxoKeyPrefix + hash)
+	if errors.Is(err, errs.ErrNotFound) {
+		return nil, fmt.Errorf("%w: utxo %s", errs.ErrNotFound, hash)
+	}
+	if err != nil {
+		return nil, err
+	}
+	utxo := &UTXO{}
+	if err := json.Unmarshal(b, utxo); err != nil {
+		return nil, err
+	}
+	return utxo, nil
+}
+
+func (s *DiskUTXOStore) Put(utxo *UTXO) error {
+	op, err := utxoOp(utxo)
+	if err != nil {
+		return err
+	}
+	return s.db.write([]dbOp{op})
+}
+
+func (s *DiskUTXOStore) Delete(hash string) error {
+	return s.db.Delete(utxoKeyPrefix + hash)
+}
+
+// WriteBatch writes all the changes of the batch as a single record, so
+// they are either all found when the store is reopened or none are.
+func (db *DB) WriteBatch(b *Batch) error {
+	ops := []dbOp{}
+	for _, block := range b.blocks {
+		op, err := blockOp(block)
+		if err != nil {
+			return err
+		}
+		ops = append(ops, op)
+	}
+	for _, tx := range b.txx {
+		op, err := txOp(tx)
+		if err != nil {
+			return err
+		}
+		ops = append(ops, op)
+	}
+	for key, utxo := range b.utxos {
+		if utxo == nil {
+			ops = append(ops, dbOp{kind: opDelete, key: utxoKeyPrefix + key})
+			continue
+		}
+		op, err := utxoOp(utxo)
+		if err != nil {
+			return err
+		}
+		ops = append(ops, op)
+	}
+	if b.head != "" {
+		ops = append(ops, dbOp{kind: opPut, key: headKey, value: []byte(b.head)})
+	}
+	return db.write(ops)
+}
+
+func blockOp(b *proto.Block) (dbOp, error) {
+	data, err := pb.Marshal(b)
+	if err != nil {
+		return dbOp{}, err
+	}
+	hash := hex.EncodeToString(types.HashBlock(b))
+	return dbOp{kind: opPut, key: blockKeyPrefix + hash, value: data}, nil
+}
+
+func txOp(tx *proto.Transaction) (dbOp, error) {
+	data, err := pb.Marshal(tx)
+	if err != nil {
+		return dbOp{}, err
+	}
+	hash := hex.EncodeToString(types.HashTransaction(tx))
+	return dbOp{kind: opPut, key: txKeyPrefix + hash, value: data}, nil
+}
+
+func utxoOp(utxo *UTXO) (dbOp, error) {
+	data, err := json.Marshal(utxo)
+	if err != nil {
+		return dbOp{}, err
+	}
+	key := fmt.Sprintf("%s_%d", utxo.Hash, utxo.OutIndex)
+	return dbOp{kind: opPut, key: utxoKeyPrefix + key, value: data}, nil
+}
@@ -0,0 +1,77 @@
+package node
+
+import (
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/DenisBytes/GoChain/errs"
+	"github.com/DenisBytes/GoChain/types"
+)
+
+// ErrOrphanBlock is returned by AddBlock for a block whose parent is not
+// known yet. The block is kept and added as soon as its parent arrives.
+var ErrOrphanBlock = errors.New("orphan block")
+
+// ErrMempoolConflict is returned by Mempool.Add for a transaction that
+// spends an output already spent by a transaction in the mempool.
+var ErrMempoolConflict = errors.New("transaction conflicts with the mempool")
+
+// ErrMempoolFull is returned by Mempool.Add for a transaction whose fee
+// rate is too low to make room for it in a full mempool.
+var ErrMempoolFull = errors.New("mempool full")
+
+// ErrReplacementRejected is returned by Mempool.Add for a transaction
+// replacing replaceable transactions of the pool without following the
+// replace-by-fee rules.
+var ErrReplacementRejected = errors.New("replacement rejected")
+
+// ErrWrongChain is returned by Handshake to a peer on another network.
+var ErrWrongChain = errors.New("peer on a different chain")
+
+// ErrRollbackFailed is returned when the changes of a failed batch could
+// not all be undone. The stores are then left half-applied and the chain
+// refuses any further block.
+var ErrRollbackFailed = errors.New("failed to roll back a batch")
+
+// ErrDBClosed is returned by the DB once it has been closed.
+var ErrDBClosed = errors.New("database closed")
+
+// statusError converts an error returned to a peer into a gRPC status,
+// so the peer can tell a message it got wrong from a failure of the
+// node.
+func statusError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return status.Error(statusCode(err), err.Error())
+}
+
+func statusCode(err error) codes.Code {
+	var (
+		txErr    *errs.TxError
+		blockErr *errs.BlockError
+	)
+	switch {
+	case errors.Is(err, errs.ErrNotFound), errors.Is(err, types.ErrTxNotInBlock):
+		return codes.NotFound
+	case errors.Is(err, errs.ErrDuplicateBlock):
+		return codes.AlreadyExists
+	case errors.Is(err, ErrMempoolFull):
+		return codes.ResourceExhausted
+	case errors.Is(err, ErrDBClosed):
+		return codes.Unavailable
+	// These may no longer hold once the node learns more blocks.
+	case errors.Is(err, errs.ErrMissingInput), errors.Is(err, errs.ErrImmatureCoinbase),
+		errors.Is(err, errs.ErrBadPrevHash), errors.Is(err, errs.ErrTimestampTooNew):
+		return codes.FailedPrecondition
+	case errors.Is(err, ErrWrongChain):
+		return codes.PermissionDenied
+	case errors.Is(err, errs.ErrMalformed), errors.Is(err, errs.ErrInvalidRequest),
+		errors.As(err, &txErr), errors.As(err, &blockErr):
+		return codes.InvalidArgument
+	default:
+		return codes.Internal
+	}
+}
@@ -0,0 +1,70 @@
+package node
+
+import (
+	"github.com/DenisBytes/GoChain/proto"
+)
+
+// ChainEvent describes how the main branch changed after a block was
+// added. Disconnected holds the blocks that left the main branch, tip
+// first, and Connected the blocks that joined it, in order. A block that
+// extends the tip is a single connected block, a reorganisation
+// disconnects the blocks down to the fork first.
+type ChainEvent struct {
+	Disconnected []*proto.Block
+	Connected    []*proto.Block
+}
+
+func (ev ChainEvent) empty() bool {
+	return len(ev.Disconnected) == 0 && len(ev.Connected) == 0
+}
+
+// ChainListener is called with the changes of the main branch. Listeners
+// are called in order, once the chain is unlocked, so they may query the
+// chain but must not add blocks to it.
+type ChainListener func(ChainEvent)
+
+// Subscribe registers listener to be called whenever the main branch
+// changes.
+func (c *Chain) Subscribe(listener ChainListener) {
+	c.notifyLock.Lock()
+	defer c.notifyLock.Unlock()
+
+	c.listeners = append(c.listeners, listener)
+}
+
+// chainEventBuilder gathers the blocks connected and disconnected while
+// the chain is locked. A block disconnected right after being connected,
+// or the other way round, cancels out, so a failed reorganisation that
+// restores the old branch leaves no trace.
+type chainEventBuilder struct {
+	event        ChainEvent
+	connected    []string
+	disconnected []string
+}
+
+func (builder *chainEventBuilder) connect(hash string, b *proto.Block) {
+	if n := len(builder.disconnected); n > 0 && builder.disconnected[n-1] == hash {
+		builder.disconnected = builder.disconnected[:n-1]
+		builder.event.Disconnected = builder.event.Disconnected[:n-1]
+		return
+	}
+	builder.connected = append(builder.connected, hash)
+	builder.event.Connected = append(builder.event.Connected, b)
+}
+
+func (builder *chainEventBuilder) disconnect(hash string, b *proto.Block) {
+	if n := len(builder.connected); n > 0 && builder.connected[n-1] == hash {
+		builder.connected = builder.connected[:n-1]
+		builder.event.Connected = builder.event.Connected[:n-1]
+		return
+	}
+	builder.disconnected = append(builder.disconnected, hash)
+	builder.event.Disconnected = append(builder.event.Disconnected, b)
+}
+
+// take returns the gathered event and starts a new one.
+func (builder *chainEventBuilder) take() ChainEvent {
+	event := builder.event
+	*builder = chainEventBuilder{}
+	return event
+}
@@ -0,0 +1,84 @@
+package node
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/DenisBytes/GoChain/proto"
+)
+
+func TestChainEvents(t *testing.T) {
+	chain := NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
+	genesis, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+
+	events := []ChainEvent{}
+	chain.Subscribe(func(ev ChainEvent) {
+		events = append(events, ev)
+	})
+
+	a1 := blockOn(genesis)
+	require.Nil(t, chain.AddBlock(a1))
+	require.Equal(t, []ChainEvent{{Connected: []*proto.Block{a1}}}, events)
+
+	// A side branch only makes an event once it is preferred.
+	b1 := blockOn(genesis)
+	b2 := blockOn(b1)
+	require.Nil(t, chain.AddBlock(b1))
+	require.Len(t, events, 1)
+	require.Nil(t, chain.AddBlock(b2))
+	require.Equal(t, ChainEvent{
+		Disconnected: []*proto.Block{a1},
+		Connected:    []*proto.Block{b1, b2},
+	}, events[1])
+
+	// A reorganisation that fails and restores the branch is no change.
+	c1 := blockOn(genesis)
+	c2 := blockOn(c1)
+	c3 := blockOn(c2, spendGenesis(t, chain, 10), spendGenesis(t, chain, 20))
+	require.Nil(t, chain.AddBlock(c1))
+	require.Nil(t, chain.AddBlock(c2))
+	require.NotNil(t, chain.AddBlock(c3))
+	requireTip(t, chain, b2)
+	require.Len(t, events, 2)
+}
+
+func TestChainListenerQueriesChain(t *testing.T) {
+	chain := NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
+	genesis, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+	a1 := blockOn(genesis)
+	a2 := blockOn(a1)
+
+	var (
+		once       sync.Once
+		inListener = make(chan struct{})
+		heights    = make(chan int, 2)
+		added      = make(chan error, 2)
+	)
+	chain.Subscribe(func(ev ChainEvent) {
+		// Give the second AddBlock time to wait for the chain.
+		once.Do(func() {
+			close(inListener)
+			time.Sleep(50 * time.Millisecond)
+		})
+		heights <- chain.Height()
+	})
+
+	go func() { added <- chain.AddBlock(a1) }()
+	<-inListener
+	go func() { added <- chain.AddBlock(a2) }()
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-added:
+			require.Nil(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("AddBlock deadlocked with a listener")
+		}
+	}
+	require.Equal(t, 1, <-heights)
+	require.Equal(t, 2, <-heights)
+}
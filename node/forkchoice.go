@@ -0,0 +1,107 @@
+package node
+
+import (
+	"sort"
+
+	"github.com/DenisBytes/GoChain/proto"
+)
+
+// BlockNode is a block in the tree of all the blocks known to a chain,
+// including the ones on side branches.
+type BlockNode struct {
+	Hash   string
+	Header *proto.Header
+	Parent *BlockNode
+	Height int
+	// Work is the cumulative work of the branch ending at this block.
+	Work uint64
+
+	invalid bool
+	undo    *blockUndo
+}
+
+func newBlockNode(hash string, header *proto.Header, parent *BlockNode) *BlockNode {
+	node := &BlockNode{
+		Hash:   hash,
+		Header: header,
+		Parent: parent,
+		Work:   blockWork(header),
+	}
+	if parent != nil {
+		node.Height = parent.Height + 1
+		node.Work += parent.Work
+	}
+	return node
+}
+
+// Ancestor returns the ancestor of the block at the given height or nil
+// if there is none.
+func (node *BlockNode) Ancestor(height int) *BlockNode {
+	if height < 0 || height > node.Height {
+		return nil
+	}
+	for node != nil && node.Height > height {
+		node = node.Parent
+	}
+	return node
+}
+
+// MedianTime returns the median timestamp of the block and its
+// ancestors, up to medianTimeBlocks of them.
+func (node *BlockNode) MedianTime() int64 {
+	timestamps := make([]int64, 0, medianTimeBlocks)
+	for ; node != nil && len(timestamps) < medianTimeBlocks; node = node.Parent {
+		timestamps = append(timestamps, node.Header.Timestamp)
+	}
+	sort.Slice(timestamps, func(i, j int) bool { return timestamps[i] < timestamps[j] })
+	return timestamps[len(timestamps)/2]
+}
+
+// blockWork is the amount of work a single block adds to its branch.
+// Blocks carry no difficulty yet, so every block is worth one unit.
+func blockWork(header *proto.Header) uint64 {
+	return 1
+}
+
+// findFork returns the last block that both branches have in common.
+func findFork(a, b *BlockNode) *BlockNode {
+	if a.Height > b.Height {
+		a = a.Ancestor(b.Height)
+	} else {
+		b = b.Ancestor(a.Height)
+	}
+	for a != b {
+		a = a.Parent
+		b = b.Parent
+	}
+	return a
+}
+
+// ForkChoice reports whether the chain should switch from the branch
+// ending at current to the branch ending at candidate. On a tie the
+// branch that was seen first is kept.
+type ForkChoice func(current, candidate *BlockNode) bool
+
+// LongestChain prefers the branch with the most blocks.
+func LongestChain(current, candidate *BlockNode) bool {
+	return candidate.Height > current.Height
+}
+
+// MostWork prefers the branch with the most cumulative work.
+func MostWork(current, candidate *BlockNode) bool {
+	return candidate.Work > current.Work
+}
+
+// HighestFinalized treats every block that is buried at least depth
+// blocks below the current tip as final and never switches to a branch
+// that would revert it. Among the branches that keep the highest
+// finalized block, rule decides.
+func HighestFinalized(depth int, rule ForkChoice) ForkChoice {
+	return func(current, candidate *BlockNode) bool {
+		fork := findFork(current, candidate)
+		if current.Height-fork.Height > depth {
+			return false
+		}
+		return rule(current, candidate)
+	}
+}
@@ -0,0 +1,240 @@
+package node
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"github.com/DenisBytes/GoChain/crypto"
+	"github.com/DenisBytes/GoChain/proto"
+	"github.com/DenisBytes/GoChain/types"
+)
+
+// maxChainIDLen bounds the length of a chain ID.
+const maxChainIDLen = 64
+
+// Genesis is the specification of a network. Every node of a network
+// must start from the same one, which they check by comparing the hash
+// of the genesis block it generates (see Hash).
+type Genesis struct {
+	ChainID     string    `json:"chain_id"`
+	GenesisTime time.Time `json:"genesis_time"`
+	// Allocations are the outputs of the genesis block, in order.
+	Allocations []GenesisAllocation `json:"allocations"`
+	// Validators are the hex encoded public keys of the validators, who
+	// take turns signing the blocks in this order (see
+	// Chain.Proposer).
+	Validators []string      `json:"validators"`
+	Params     GenesisParams `json:"params"`
+}
+
+// GenesisAllocation credits Amount to the hex encoded Address.
+type GenesisAllocation struct {
+	Address string `json:"address"`
+	Amount  int64  `json:"amount"`
+}
+
+// GenesisParams are the consensus parameters of a network.
+type GenesisParams struct {
+	CoinbaseMaturity int          `json:"coinbase_maturity"`
+	BlockReward      RewardParams `json:"block_reward"`
+}
+
+// RewardParams describe a RewardSchedule: Initial is halved every
+// HalvingInterval blocks, or never when it is zero, and the reward never
+// drops below Tail.
+type RewardParams struct {
+	Initial         int64 `json:"initial"`
+	HalvingInterval int   `json:"halving_interval,omitempty"`
+	Tail            int64 `json:"tail,omitempty"`
+}
+
+// Schedule returns the RewardSchedule described by p.
+func (p RewardParams) Schedule() RewardSchedule {
+	var schedule RewardSchedule = FixedReward(p.Initial)
+	if p.HalvingInterval > 0 {
+		schedule = HalvingReward{Initial: p.Initial, Interval: p.HalvingInterval}
+	}
+	if p.Tail > 0 {
+		schedule = TailEmission{Schedule: schedule, Tail: p.Tail}
+	}
+	return schedule
+}
+
+// DevGenesis returns the genesis of the development network, which
+// allocates 1000 to a single key and makes it the only validator. That
+// key is public, never use it for a real network.
+func DevGenesis() *Genesis {
+	return &Genesis{
+		ChainID:     "gochain-dev",
+		GenesisTime: time.Unix(0, 0).UTC(),
+		Allocations: []GenesisAllocation{
+			{Address: "50933f55ceb146484c3db5d25489a4ba7f4392f1", Amount: 1000},
+		},
+		Validators: []string{"93d59bfe9f9d6fef119ed09450933f55ceb146484c3db5d25489a4ba7f4392f1"},
+		Params: GenesisParams{
+			CoinbaseMaturity: defaultCoinbaseMaturity,
+			BlockReward:      RewardParams{Initial: 50, HalvingInterval: 210000},
+		},
+	}
+}
+
+// LoadGenesis reads and validates the JSON genesis specification at
+// path.
+func LoadGenesis(path string) (*Genesis, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	g := &Genesis{}
+	if err := dec.Decode(g); err != nil {
+		return nil, fmt.Errorf("genesis %s: %w", path, err)
+	}
+	if err := g.Validate(); err != nil {
+		return nil, fmt.Errorf("genesis %s: %w", path, err)
+	}
+	return g, nil
+}
+
+// Validate checks the specification is complete and well formed.
+func (g *Genesis) Validate() error {
+	if g.ChainID == "" || len(g.ChainID) > maxChainIDLen {
+		return fmt.Errorf("chain id must have 1 to %d bytes", maxChainIDLen)
+	}
+	if g.GenesisTime.IsZero() {
+		return fmt.Errorf("no genesis time")
+	}
+	if len(g.Allocations) == 0 {
+		return fmt.Errorf("no allocations")
+	}
+	total := int64(0)
+	for i, alloc := range g.Allocations {
+		if _, err := decodeHex(alloc.Address, crypto.AddressLen); err != nil {
+			return fmt.Errorf("allocation %d: address: %w", i, err)
+		}
+		if alloc.Amount <= 0 {
+			return fmt.Errorf("allocation %d: amount %d is not positive", i, alloc.Amount)
+		}
+		if alloc.Amount > math.MaxInt64-total {
+			return fmt.Errorf("allocation %d: total supply overflows", i)
+		}
+		total += alloc.Amount
+	}
+	if len(g.Validators) == 0 {
+		return fmt.Errorf("no validators")
+	}
+	if _, err := g.validatorKeys(); err != nil {
+		return err
+	}
+	p := g.Params
+	if p.CoinbaseMaturity < 0 {
+		return fmt.Errorf("negative coinbase maturity")
+	}
+	if r := p.BlockReward; r.Initial < 0 || r.HalvingInterval < 0 || r.Tail < 0 {
+		return fmt.Errorf("negative block reward parameter")
+	}
+	return nil
+}
+
+// validatorKeys decodes the public keys of the validators.
+func (g *Genesis) validatorKeys() ([]*crypto.PublicKey, error) {
+	keys := make([]*crypto.PublicKey, len(g.Validators))
+	seen := make(map[string]bool)
+	for i, v := range g.Validators {
+		b, err := decodeHex(v, crypto.PubKeyLen)
+		if err != nil {
+			return nil, fmt.Errorf("validator %d: %w", i, err)
+		}
+		if seen[string(b)] {
+			return nil, fmt.Errorf("validator %d: duplicate key %s", i, v)
+		}
+		seen[string(b)] = true
+		if keys[i], err = crypto.PublicKeyFromBytes(b); err != nil {
+			return nil, fmt.Errorf("validator %d: %w", i, err)
+		}
+	}
+	return keys, nil
+}
+
+func decodeHex(s string, size int) ([]byte, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != size {
+		return nil, fmt.Errorf("%d bytes instead of %d", len(b), size)
+	}
+	return b, nil
+}
+
+// Block generates the genesis block. It holds a single transaction
+// without inputs paying the allocations. The genesis block has no
+// parent, so its PrevHash commits to the rest of the specification
+// instead: the chain ID, the validators and the parameters. The block is
+// not signed, nodes trust it because they were given the specification.
+func (g *Genesis) Block() (*proto.Block, error) {
+	if err := g.Validate(); err != nil {
+		return nil, err
+	}
+	tx := &proto.Transaction{
+		Version: 1,
+		Inputs:  []*proto.TxInput{},
+	}
+	for _, alloc := range g.Allocations {
+		address, _ := hex.DecodeString(alloc.Address)
+		tx.Outputs = append(tx.Outputs, &proto.TxOutput{
+			Amount:  alloc.Amount,
+			Address: address,
+		})
+	}
+	block := &proto.Block{
+		Header: &proto.Header{
+			Version:   blockVersion,
+			PrevHash:  g.paramsHash(),
+			Timestamp: g.GenesisTime.UnixNano(),
+		},
+		Transactions: []*proto.Transaction{tx},
+	}
+	block.Header.RootHash = types.MerkleRoot(block)
+	return block, nil
+}
+
+// Hash returns the hex encoded hash of the genesis block.
+func (g *Genesis) Hash() (string, error) {
+	b, err := g.Block()
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(types.HashBlock(b)), nil
+}
+
+// paramsHash is a SHA-256 of the chain ID, the validators and the
+// parameters, encoded like the messages are by the types package.
+func (g *Genesis) paramsHash() []byte {
+	buf := appendString(nil, g.ChainID)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(g.Validators)))
+	for _, v := range g.Validators {
+		key, _ := hex.DecodeString(v)
+		buf = appendString(buf, string(key))
+	}
+	p := g.Params
+	buf = binary.BigEndian.AppendUint64(buf, uint64(p.CoinbaseMaturity))
+	buf = binary.BigEndian.AppendUint64(buf, uint64(p.BlockReward.Initial))
+	buf = binary.BigEndian.AppendUint64(buf, uint64(p.BlockReward.HalvingInterval))
+	buf = binary.BigEndian.AppendUint64(buf, uint64(p.BlockReward.Tail))
+	hash := sha256.Sum256(buf)
+	return hash[:]
+}
+
+func appendString(buf []byte, s string) []byte {
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(s)))
+	return append(buf, s...)
+}
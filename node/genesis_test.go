@@ -0,0 +1,137 @@
+package node
+
+import (
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DenisBytes/GoChain/types"
+)
+
+func TestLoadGenesis(t *testing.T) {
+	g, err := LoadGenesis("testdata/genesis.json")
+	require.Nil(t, err)
+	require.Equal(t, "gochain-testnet-1", g.ChainID)
+	require.Len(t, g.Validators, 2)
+
+	chain := NewChain(NewMemoryBlockStore(), NewMemoryTXStore(), WithGenesis(g))
+	genesis, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+	hash, err := g.Hash()
+	require.Nil(t, err)
+	assert.Equal(t, hash, hex.EncodeToString(types.HashBlock(genesis)))
+	assert.Equal(t, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC).UnixNano(), genesis.Header.Timestamp)
+
+	assert.Equal(t, int64(2_000_000), chain.TotalSupply())
+	for i, alloc := range g.Allocations {
+		utxo, err := chain.utxoStore.Get(utxoKey(genesis.Transactions[0], i))
+		require.Nil(t, err)
+		assert.Equal(t, alloc.Amount, utxo.Amount)
+		assert.Equal(t, alloc.Address, hex.EncodeToString(utxo.Address))
+	}
+
+	assert.Equal(t, 20, chain.coinbaseMaturity)
+	assert.Equal(t, int64(100), chain.BlockReward(1))
+	assert.Equal(t, int64(50), chain.BlockReward(100_000))
+	assert.Equal(t, int64(1), chain.BlockReward(10_000_000))
+}
+
+func TestLoadGenesisRejectsUnknownFields(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "genesis.json")
+	require.Nil(t, os.WriteFile(path, []byte(`{"chain_id": "x", "chainid": "y"}`), 0o644))
+	_, err := LoadGenesis(path)
+	require.NotNil(t, err)
+}
+
+func TestGenesisHashCommitsToSpec(t *testing.T) {
+	base, err := DevGenesis().Hash()
+	require.Nil(t, err)
+
+	changes := map[string]func(g *Genesis){
+		"chain id":     func(g *Genesis) { g.ChainID = "gochain-other" },
+		"genesis time": func(g *Genesis) { g.GenesisTime = g.GenesisTime.Add(time.Second) },
+		"amount":       func(g *Genesis) { g.Allocations[0].Amount++ },
+		"allocation": func(g *Genesis) {
+			g.Allocations = append(g.Allocations, GenesisAllocation{Address: strings.Repeat("ab", 20), Amount: 1})
+		},
+		"validators":        func(g *Genesis) { g.Validators = []string{strings.Repeat("ab", 32)} },
+		"coinbase maturity": func(g *Genesis) { g.Params.CoinbaseMaturity++ },
+		"block reward":      func(g *Genesis) { g.Params.BlockReward.Tail = 1 },
+	}
+	for name, change := range changes {
+		t.Run(name, func(t *testing.T) {
+			g := DevGenesis()
+			change(g)
+			hash, err := g.Hash()
+			require.Nil(t, err)
+			assert.NotEqual(t, base, hash)
+		})
+	}
+
+	// The hex encoding of the addresses does not matter.
+	g := DevGenesis()
+	g.Allocations[0].Address = strings.ToUpper(g.Allocations[0].Address)
+	hash, err := g.Hash()
+	require.Nil(t, err)
+	assert.Equal(t, base, hash)
+}
+
+func TestGenesisValidate(t *testing.T) {
+	tests := map[string]func(g *Genesis){
+		"no chain id":       func(g *Genesis) { g.ChainID = "" },
+		"long chain id":     func(g *Genesis) { g.ChainID = strings.Repeat("x", maxChainIDLen+1) },
+		"no genesis time":   func(g *Genesis) { g.GenesisTime = time.Time{} },
+		"no allocations":    func(g *Genesis) { g.Allocations = nil },
+		"bad address":       func(g *Genesis) { g.Allocations[0].Address = "zz" },
+		"short address":     func(g *Genesis) { g.Allocations[0].Address = "abcd" },
+		"zero amount":       func(g *Genesis) { g.Allocations[0].Amount = 0 },
+		"short validator":   func(g *Genesis) { g.Validators = []string{"abcd"} },
+		"no validators":     func(g *Genesis) { g.Validators = nil },
+		"negative maturity": func(g *Genesis) { g.Params.CoinbaseMaturity = -1 },
+		"negative reward":   func(g *Genesis) { g.Params.BlockReward.Initial = -1 },
+		"duplicate validator": func(g *Genesis) {
+			g.Validators = []string{strings.Repeat("ab", 32), strings.Repeat("AB", 32)}
+		},
+		"supply overflow": func(g *Genesis) {
+			g.Allocations = append(g.Allocations, GenesisAllocation{Address: g.Allocations[0].Address, Amount: 1<<63 - 1})
+		},
+	}
+	require.Nil(t, DevGenesis().Validate())
+	for name, change := range tests {
+		t.Run(name, func(t *testing.T) {
+			g := DevGenesis()
+			change(g)
+			require.NotNil(t, g.Validate())
+			_, err := OpenChain(NewMemoryBlockStore(), NewMemoryTXStore(), NewMemoryUTXOStore(), WithGenesis(g))
+			require.NotNil(t, err)
+		})
+	}
+}
+
+func TestOpenChainWithOtherGenesis(t *testing.T) {
+	dir := t.TempDir()
+	db, err := OpenDB(dir)
+	require.Nil(t, err)
+	chain, err := openDiskChain(db)
+	require.Nil(t, err)
+	require.Nil(t, chain.AddBlock(randomBlock(t, chain)))
+	require.Nil(t, db.Close())
+
+	db, err = OpenDB(dir)
+	require.Nil(t, err)
+	defer db.Close()
+	g, err := LoadGenesis("testdata/genesis.json")
+	require.Nil(t, err)
+	_, err = OpenChain(NewDiskBlockStore(db), NewDiskTXStore(db), NewDiskUTXOStore(db), WithBatchWriter(db), WithGenesis(g))
+	require.ErrorContains(t, err, "different genesis")
+
+	chain, err = openDiskChain(db)
+	require.Nil(t, err)
+	require.Equal(t, 1, chain.Height())
+}
@@ -0,0 +1,696 @@
+package node
+
+import (
+	"container/heap"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	pb "google.golang.org/protobuf/proto"
+
+	"github.com/DenisBytes/GoChain/proto"
+	"github.com/DenisBytes/GoChain/types"
+)
+
+const (
+	defaultMempoolBytes = 32 << 20
+	defaultMempoolTxs   = 20000
+	defaultMempoolTTL   = time.Hour * 24
+
+	// maxReplacements is the number of transactions a replacement may
+	// evict, descendants included.
+	maxReplacements = 100
+	// minReplacementFeeRate is the fee per byte a replacement pays on
+	// top of the fees of the transactions it replaces, so a replacement
+	// cannot be relayed for free.
+	minReplacementFeeRate = 1
+
+	// mempoolFile is the name of the file in the data directory the
+	// mempool is saved to on shutdown.
+	mempoolFile        = "mempool.dat"
+	mempoolDumpVersion = 1
+)
+
+// feeRate is a fee paid for a number of bytes. Fee rates are compared
+// without dividing so no precision is lost.
+type feeRate struct {
+	fee  int64
+	size int
+}
+
+func (r feeRate) add(other feeRate) feeRate {
+	return feeRate{fee: r.fee + other.fee, size: r.size + other.size}
+}
+
+func (r feeRate) sub(other feeRate) feeRate {
+	return feeRate{fee: r.fee - other.fee, size: r.size - other.size}
+}
+
+// cmp returns -1, 0 or 1 when r is lower than, equal to or higher than
+// other.
+func (r feeRate) cmp(other feeRate) int {
+	a, b := r.fee*int64(other.size), other.fee*int64(r.size)
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+type mempoolEntry struct {
+	hash  string
+	tx    *proto.Transaction
+	fee   int64
+	size  int
+	added time.Time
+	// parents and children hold the hashes of the transactions of the
+	// pool this one spends from and that spend from it.
+	parents  map[string]bool
+	children map[string]bool
+}
+
+func (e *mempoolEntry) feeRate() feeRate {
+	return feeRate{fee: e.fee, size: e.size}
+}
+
+// before breaks ties between entries of equal fee rate in favour of the
+// one that arrived first.
+func (e *mempoolEntry) before(other *mempoolEntry) bool {
+	if !e.added.Equal(other.added) {
+		return e.added.Before(other.added)
+	}
+	return e.hash < other.hash
+}
+
+// Mempool holds the transactions waiting to be included in a block,
+// prioritised by fee rate. It is bounded in bytes, in number of
+// transactions and in the time a transaction may wait.
+//
+// A transaction is mined together with its unconfirmed ancestors, so
+// transactions are ranked by the fee rate of that package: a child
+// paying a high fee pulls its low fee parent into the block.
+type Mempool struct {
+	txx map[string]*mempoolEntry
+	// spends maps the outpoints spent by the transactions in the pool
+	// to the hash of the spending transaction.
+	spends   map[string]string
+	size     int
+	maxBytes int
+	maxCount int
+	ttl      time.Duration
+	lock     sync.RWMutex
+}
+
+func NewMemPool(maxBytes, maxCount int, ttl time.Duration) *Mempool {
+	return &Mempool{
+		txx:      make(map[string]*mempoolEntry),
+		spends:   make(map[string]string),
+		maxBytes: maxBytes,
+		maxCount: maxCount,
+		ttl:      ttl,
+	}
+}
+
+// Clear empties the pool and returns its transactions, parents first
+// and by decreasing fee rate.
+func (pool *Mempool) Clear() []*proto.Transaction {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	txx := pool.selectTransactions(math.MaxInt, math.MaxInt)
+	pool.txx = make(map[string]*mempoolEntry)
+	pool.spends = make(map[string]string)
+	pool.size = 0
+	return txx
+}
+
+// Transactions returns the transactions of the pool, parents first and
+// by decreasing fee rate.
+func (pool *Mempool) Transactions() []*proto.Transaction {
+	return pool.Select(math.MaxInt, math.MaxInt)
+}
+
+// Select returns the transactions with the best fee rate that fit in
+// maxBytes and maxCount. A transaction is only selected after all its
+// parents in the pool, so the result can be included in a block as is.
+func (pool *Mempool) Select(maxBytes, maxCount int) []*proto.Transaction {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	pool.expire()
+	return pool.selectTransactions(maxBytes, maxCount)
+}
+
+// selectTransactions repeatedly picks the transaction whose package, made
+// of itself and its ancestors not picked yet, has the best fee rate and
+// picks the whole package.
+func (pool *Mempool) selectTransactions(maxBytes, maxCount int) []*proto.Transaction {
+	var (
+		txx        = []*proto.Transaction{}
+		selected   = make(map[string]bool)
+		skipped    = make(map[string]bool)
+		ancestors  = make(map[string]map[string]bool, len(pool.txx))
+		packages   = make(map[string]feeRate, len(pool.txx))
+		candidates = &packageHeap{}
+	)
+	for hash, entry := range pool.txx {
+		ancestors[hash] = pool.ancestors(entry)
+		rate := entry.feeRate()
+		for ancestor := range ancestors[hash] {
+			rate = rate.add(pool.txx[ancestor].feeRate())
+		}
+		packages[hash] = rate
+		heap.Push(candidates, &packageCandidate{entry: entry, rate: rate})
+	}
+
+	for candidates.Len() > 0 && len(txx) < maxCount {
+		candidate := heap.Pop(candidates).(*packageCandidate)
+		hash := candidate.entry.hash
+		// Candidates are pushed again when their package changes, the
+		// outdated ones are skipped.
+		if selected[hash] || skipped[hash] || packages[hash] != candidate.rate {
+			continue
+		}
+
+		pkg := []*mempoolEntry{candidate.entry}
+		for ancestor := range ancestors[hash] {
+			if !selected[ancestor] {
+				pkg = append(pkg, pool.txx[ancestor])
+			}
+		}
+		if candidate.rate.size > maxBytes || len(txx)+len(pkg) > maxCount {
+			skipped[hash] = true
+			continue
+		}
+		// An ancestor always has fewer ancestors than its descendants.
+		sort.Slice(pkg, func(i, j int) bool {
+			a, b := len(ancestors[pkg[i].hash]), len(ancestors[pkg[j].hash])
+			if a != b {
+				return a < b
+			}
+			return pkg[i].hash < pkg[j].hash
+		})
+
+		for _, entry := range pkg {
+			selected[entry.hash] = true
+			txx = append(txx, entry.tx)
+			maxBytes -= entry.size
+			for _, descendant := range pool.descendants(entry)[1:] {
+				if selected[descendant.hash] {
+					continue
+				}
+				packages[descendant.hash] = packages[descendant.hash].sub(entry.feeRate())
+				heap.Push(candidates, &packageCandidate{entry: descendant, rate: packages[descendant.hash]})
+			}
+		}
+	}
+	return txx
+}
+
+// Remove removes the given transactions from the pool, typically because
+// they were included in a block. Their children stay in the pool.
+func (pool *Mempool) Remove(txx ...*proto.Transaction) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	for _, tx := range txx {
+		if entry, ok := pool.txx[hex.EncodeToString(types.HashTransaction(tx))]; ok {
+			pool.remove(entry)
+		}
+	}
+}
+
+// RemoveConfirmed removes the transactions of a connected block from the
+// pool, together with the transactions spending the same outputs and
+// their descendants, which can no longer be mined.
+func (pool *Mempool) RemoveConfirmed(txx ...*proto.Transaction) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	for _, tx := range txx {
+		if entry, ok := pool.txx[hex.EncodeToString(types.HashTransaction(tx))]; ok {
+			pool.remove(entry)
+		}
+		for _, input := range tx.Inputs {
+			if spender, ok := pool.spends[outpointKey(input)]; ok {
+				pool.removeWithDescendants(pool.txx[spender])
+			}
+		}
+	}
+}
+
+func (pool *Mempool) Len() int {
+	pool.lock.RLock()
+	defer pool.lock.RUnlock()
+
+	return len(pool.txx)
+}
+
+// Size returns the size in bytes of the transactions in the pool.
+func (pool *Mempool) Size() int {
+	pool.lock.RLock()
+	defer pool.lock.RUnlock()
+
+	return pool.size
+}
+
+func (pool *Mempool) Has(tx *proto.Transaction) bool {
+	return pool.HasHash(hex.EncodeToString(types.HashTransaction(tx)))
+}
+
+// HasHash reports whether the transaction with the given hex encoded
+// hash is in the pool.
+func (pool *Mempool) HasHash(hash string) bool {
+	pool.lock.RLock()
+	defer pool.lock.RUnlock()
+
+	_, ok := pool.txx[hash]
+	return ok
+}
+
+// Ancestors returns the transactions of the pool tx spends from,
+// directly or not, parents first. Their outputs are what tx may spend on
+// top of the UTXO set of the chain.
+func (pool *Mempool) Ancestors(tx *proto.Transaction) []*proto.Transaction {
+	pool.lock.RLock()
+	defer pool.lock.RUnlock()
+
+	entry := &mempoolEntry{parents: make(map[string]bool)}
+	for _, input := range tx.Inputs {
+		if parent := hex.EncodeToString(input.PrevTxHash); pool.txx[parent] != nil {
+			entry.parents[parent] = true
+		}
+	}
+	var (
+		entries = []*mempoolEntry{}
+		depth   = make(map[string]int)
+	)
+	for hash := range pool.ancestors(entry) {
+		entries = append(entries, pool.txx[hash])
+		depth[hash] = len(pool.ancestors(pool.txx[hash]))
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if a, b := depth[entries[i].hash], depth[entries[j].hash]; a != b {
+			return a < b
+		}
+		return entries[i].hash < entries[j].hash
+	})
+
+	txx := make([]*proto.Transaction, len(entries))
+	for i, e := range entries {
+		txx[i] = e.tx
+	}
+	return txx
+}
+
+// Add adds tx paying fee to the pool. It returns false if the transaction
+// is already in the pool.
+//
+// A transaction spending an output already spent in the pool replaces
+// the conflicting transactions and their descendants if they all opted
+// in to replace-by-fee and tx pays more, otherwise ErrMempoolConflict or
+// ErrReplacementRejected is returned. When the pool is full the
+// transactions with the lowest fee rate are evicted to make room, or
+// ErrMempoolFull is returned if tx has the lowest fee rate.
+func (pool *Mempool) Add(tx *proto.Transaction, fee int64) (bool, error) {
+	return pool.add(tx, fee, time.Now())
+}
+
+// add adds tx like Add, as if it had been received at the given time.
+func (pool *Mempool) add(tx *proto.Transaction, fee int64, added time.Time) (bool, error) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	hash := hex.EncodeToString(types.HashTransaction(tx))
+	if _, ok := pool.txx[hash]; ok {
+		return false, nil
+	}
+	pool.expire()
+
+	entry := &mempoolEntry{
+		hash:     hash,
+		tx:       tx,
+		fee:      fee,
+		size:     pb.Size(tx),
+		added:    added,
+		parents:  make(map[string]bool),
+		children: make(map[string]bool),
+	}
+	for _, input := range tx.Inputs {
+		if parent := hex.EncodeToString(input.PrevTxHash); pool.txx[parent] != nil {
+			entry.parents[parent] = true
+		}
+	}
+
+	replaced, err := pool.replacements(entry)
+	if err != nil {
+		return false, err
+	}
+	victims, err := pool.victims(entry, replaced)
+	if err != nil {
+		return false, err
+	}
+	for _, e := range replaced {
+		pool.remove(e)
+	}
+	for _, victim := range victims {
+		pool.removeWithDescendants(victim)
+	}
+
+	pool.txx[hash] = entry
+	pool.size += entry.size
+	for _, input := range tx.Inputs {
+		pool.spends[outpointKey(input)] = hash
+	}
+	for parent := range entry.parents {
+		pool.txx[parent].children[hash] = true
+	}
+	for i := range tx.Outputs {
+		if child, ok := pool.spends[fmt.Sprintf("%s_%d", hash, i)]; ok {
+			entry.children[child] = true
+			pool.txx[child].parents[hash] = true
+		}
+	}
+	return true, nil
+}
+
+// Dump saves the transactions of the pool to path, parents first, with
+// the time they were received. The file is written next to path and
+// renamed, so a crash never leaves a partial dump behind.
+//
+// The dump starts with a version byte followed by a record per
+// transaction: the uvarint length of the encoded transaction, the time
+// it was received in nanoseconds and the encoded transaction.
+func (pool *Mempool) Dump(path string) error {
+	pool.lock.RLock()
+	defer pool.lock.RUnlock()
+
+	buf := []byte{mempoolDumpVersion}
+	for _, tx := range pool.selectTransactions(math.MaxInt, math.MaxInt) {
+		b, err := pb.Marshal(tx)
+		if err != nil {
+			return err
+		}
+		entry := pool.txx[hex.EncodeToString(types.HashTransaction(tx))]
+		buf = binary.AppendUvarint(buf, uint64(len(b)))
+		buf = binary.BigEndian.AppendUint64(buf, uint64(entry.added.UnixNano()))
+		buf = append(buf, b...)
+	}
+
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(buf); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// dumpedTx is a transaction read back from a mempool dump.
+type dumpedTx struct {
+	tx    *proto.Transaction
+	added time.Time
+}
+
+// readMempoolDump returns the transactions saved by Mempool.Dump, in the
+// order they were saved. A missing file is an empty mempool.
+func readMempoolDump(path string) ([]dumpedTx, error) {
+	buf, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(buf) == 0 || buf[0] != mempoolDumpVersion {
+		return nil, fmt.Errorf("mempool dump %s: unknown version", path)
+	}
+
+	txx := []dumpedTx{}
+	for buf = buf[1:]; len(buf) > 0; {
+		size, n := binary.Uvarint(buf)
+		// Checked in two steps so a huge size cannot wrap 8+size.
+		if n <= 0 || len(buf)-n < 8 || size > uint64(len(buf)-n-8) {
+			return nil, fmt.Errorf("mempool dump %s: truncated record", path)
+		}
+		buf = buf[n:]
+		added := time.Unix(0, int64(binary.BigEndian.Uint64(buf)))
+		tx := &proto.Transaction{}
+		if err := pb.Unmarshal(buf[8:8+size], tx); err != nil {
+			return nil, fmt.Errorf("mempool dump %s: %w", path, err)
+		}
+		txx = append(txx, dumpedTx{tx: tx, added: added})
+		buf = buf[8+size:]
+	}
+	return txx, nil
+}
+
+// replacements returns the transactions entry replaces: the ones
+// spending the same outputs and their descendants. The replacement must
+// pay for all of them and for its own relay, have a better fee rate than
+// the transactions it conflicts with, and not evict too many.
+func (pool *Mempool) replacements(entry *mempoolEntry) (map[string]*mempoolEntry, error) {
+	conflicts := make(map[string]*mempoolEntry)
+	for _, input := range entry.tx.Inputs {
+		key := outpointKey(input)
+		spender, ok := pool.spends[key]
+		if !ok {
+			continue
+		}
+		conflict := pool.txx[spender]
+		if !conflict.tx.Replaceable {
+			return nil, fmt.Errorf("%w: %s is already spent by %s", ErrMempoolConflict, key, spender)
+		}
+		conflicts[spender] = conflict
+	}
+	if len(conflicts) == 0 {
+		return nil, nil
+	}
+
+	replaced := make(map[string]*mempoolEntry)
+	for _, conflict := range conflicts {
+		if entry.feeRate().cmp(conflict.feeRate()) <= 0 {
+			return nil, fmt.Errorf("%w: fee rate not higher than the one of %s", ErrReplacementRejected, conflict.hash)
+		}
+		for _, e := range pool.descendants(conflict) {
+			replaced[e.hash] = e
+		}
+	}
+	if len(replaced) > maxReplacements {
+		return nil, fmt.Errorf("%w: replaces %d transactions", ErrReplacementRejected, len(replaced))
+	}
+	for ancestor := range pool.ancestors(entry) {
+		if replaced[ancestor] != nil {
+			return nil, fmt.Errorf("%w: spends the outputs of %s it replaces", ErrReplacementRejected, ancestor)
+		}
+	}
+	fees := int64(0)
+	for _, e := range replaced {
+		fees += e.fee
+	}
+	if required := fees + int64(minReplacementFeeRate*entry.size); entry.fee < required {
+		return nil, fmt.Errorf("%w: pays %d but at least %d is required", ErrReplacementRejected, entry.fee, required)
+	}
+	return replaced, nil
+}
+
+// victims returns the transactions to evict so entry fits in the pool
+// once the replaced transactions are gone. Transactions are evicted with
+// their descendants by increasing fee rate, counting the one of their
+// descendants so a parent is kept for a child paying for it. Only
+// transactions with a lower fee rate than entry are evicted, and never
+// one entry depends on.
+func (pool *Mempool) victims(entry *mempoolEntry, replaced map[string]*mempoolEntry) ([]*mempoolEntry, error) {
+	var (
+		count = len(pool.txx) + 1
+		size  = pool.size + entry.size
+	)
+	for _, e := range replaced {
+		count--
+		size -= e.size
+	}
+	if count <= pool.maxCount && size <= pool.maxBytes {
+		return nil, nil
+	}
+
+	var (
+		entries = make([]*mempoolEntry, 0, len(pool.txx))
+		scores  = make(map[string]feeRate, len(pool.txx))
+	)
+	for hash, e := range pool.txx {
+		if replaced[hash] != nil {
+			continue
+		}
+		entries = append(entries, e)
+		scores[hash] = pool.evictionScore(e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if c := scores[entries[i].hash].cmp(scores[entries[j].hash]); c != 0 {
+			return c < 0
+		}
+		return entries[j].before(entries[i])
+	})
+
+	var (
+		ancestors = pool.ancestors(entry)
+		evicted   = make(map[string]bool)
+		victims   = []*mempoolEntry{}
+	)
+	for _, e := range entries {
+		if count <= pool.maxCount && size <= pool.maxBytes {
+			break
+		}
+		if evicted[e.hash] {
+			continue
+		}
+		if entry.feeRate().cmp(scores[e.hash]) <= 0 || ancestors[e.hash] {
+			return nil, fmt.Errorf("%w: fee rate too low", ErrMempoolFull)
+		}
+		victims = append(victims, e)
+		for _, d := range pool.descendants(e) {
+			if !evicted[d.hash] && replaced[d.hash] == nil {
+				evicted[d.hash] = true
+				count--
+				size -= d.size
+			}
+		}
+	}
+	if count > pool.maxCount || size > pool.maxBytes {
+		return nil, fmt.Errorf("%w: transaction too large", ErrMempoolFull)
+	}
+	return victims, nil
+}
+
+// evictionScore is the best of the fee rate of entry alone and of entry
+// with its descendants.
+func (pool *Mempool) evictionScore(entry *mempoolEntry) feeRate {
+	rate := feeRate{}
+	for _, d := range pool.descendants(entry) {
+		rate = rate.add(d.feeRate())
+	}
+	if entry.feeRate().cmp(rate) > 0 {
+		return entry.feeRate()
+	}
+	return rate
+}
+
+// ancestors returns the hashes of the transactions of the pool entry
+// depends on, directly or not.
+func (pool *Mempool) ancestors(entry *mempoolEntry) map[string]bool {
+	ancestors := make(map[string]bool)
+	stack := []*mempoolEntry{entry}
+	for len(stack) > 0 {
+		e := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for parent := range e.parents {
+			if !ancestors[parent] {
+				ancestors[parent] = true
+				stack = append(stack, pool.txx[parent])
+			}
+		}
+	}
+	return ancestors
+}
+
+// descendants returns entry and the transactions of the pool that depend
+// on it, directly or not.
+func (pool *Mempool) descendants(entry *mempoolEntry) []*mempoolEntry {
+	var (
+		seen        = map[string]bool{entry.hash: true}
+		descendants = []*mempoolEntry{entry}
+	)
+	for i := 0; i < len(descendants); i++ {
+		for child := range descendants[i].children {
+			if !seen[child] {
+				seen[child] = true
+				descendants = append(descendants, pool.txx[child])
+			}
+		}
+	}
+	return descendants
+}
+
+func (pool *Mempool) expire() {
+	now := time.Now()
+	for _, entry := range pool.txx {
+		if now.Sub(entry.added) > pool.ttl {
+			pool.removeWithDescendants(entry)
+		}
+	}
+}
+
+// removeWithDescendants removes entry and the transactions spending its
+// outputs, which cannot be mined without it.
+func (pool *Mempool) removeWithDescendants(entry *mempoolEntry) {
+	descendants := pool.descendants(entry)
+	for i := len(descendants) - 1; i >= 0; i-- {
+		pool.remove(descendants[i])
+	}
+}
+
+func (pool *Mempool) remove(entry *mempoolEntry) {
+	if pool.txx[entry.hash] != entry {
+		return
+	}
+	delete(pool.txx, entry.hash)
+	pool.size -= entry.size
+	for _, input := range entry.tx.Inputs {
+		delete(pool.spends, outpointKey(input))
+	}
+	for parent := range entry.parents {
+		delete(pool.txx[parent].children, entry.hash)
+	}
+	for child := range entry.children {
+		delete(pool.txx[child].parents, entry.hash)
+	}
+}
+
+// packageCandidate is a transaction and the fee rate of its package when
+// it was pushed on the heap.
+type packageCandidate struct {
+	entry *mempoolEntry
+	rate  feeRate
+}
+
+// packageHeap orders candidates by decreasing package fee rate.
+type packageHeap []*packageCandidate
+
+func (h packageHeap) Len() int { return len(h) }
+func (h packageHeap) Less(i, j int) bool {
+	if c := h[i].rate.cmp(h[j].rate); c != 0 {
+		return c > 0
+	}
+	return h[i].entry.before(h[j].entry)
+}
+func (h packageHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *packageHeap) Push(x any) {
+	*h = append(*h, x.(*packageCandidate))
+}
+
+func (h *packageHeap) Pop() any {
+	old := *h
+	candidate := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return candidate
+}
@@ -0,0 +1,253 @@
+package node
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	pb "google.golang.org/protobuf/proto"
+
+	"github.com/DenisBytes/GoChain/proto"
+	"github.com/DenisBytes/GoChain/types"
+	"github.com/DenisBytes/GoChain/util"
+)
+
+// mempoolTx returns a transaction spending the first output of parent,
+// or an unknown output when parent is nil. The mempool does not look at
+// signatures, so none is set.
+func mempoolTx(parent *proto.Transaction) *proto.Transaction {
+	prevHash := util.RandomHash()
+	if parent != nil {
+		prevHash = types.HashTransaction(parent)
+	}
+	return &proto.Transaction{
+		Version: 1,
+		Inputs: []*proto.TxInput{
+			{
+				PrevTxHash: prevHash,
+				PublicKey:  util.RandomHash(),
+			},
+		},
+		Outputs: []*proto.TxOutput{
+			{
+				Amount:  100,
+				Address: util.RandomHash()[:20],
+			},
+		},
+	}
+}
+
+func addToMempool(t *testing.T, pool *Mempool, tx *proto.Transaction, fee int64) {
+	added, err := pool.Add(tx, fee)
+	require.Nil(t, err)
+	require.True(t, added)
+}
+
+func TestMempoolOrdersByFeeRate(t *testing.T) {
+	pool := NewMemPool(defaultMempoolBytes, defaultMempoolTxs, time.Hour)
+	low, mid, high := mempoolTx(nil), mempoolTx(nil), mempoolTx(nil)
+	addToMempool(t, pool, mid, 20)
+	addToMempool(t, pool, low, 10)
+	addToMempool(t, pool, high, 30)
+
+	require.Equal(t, []*proto.Transaction{high, mid, low}, pool.Transactions())
+	require.Equal(t, []*proto.Transaction{high, mid}, pool.Select(defaultMempoolBytes, 2))
+	require.Equal(t, []*proto.Transaction{high}, pool.Select(pb.Size(high), 10))
+	require.Equal(t, pb.Size(low)+pb.Size(mid)+pb.Size(high), pool.Size())
+
+	require.Equal(t, []*proto.Transaction{high, mid, low}, pool.Clear())
+	require.Equal(t, 0, pool.Len())
+	require.Equal(t, 0, pool.Size())
+}
+
+func TestMempoolSelectsParentsFirst(t *testing.T) {
+	pool := NewMemPool(defaultMempoolBytes, defaultMempoolTxs, time.Hour)
+	parent := mempoolTx(nil)
+	child := mempoolTx(parent)
+	other := mempoolTx(nil)
+	addToMempool(t, pool, parent, 1)
+	addToMempool(t, pool, child, 10)
+	addToMempool(t, pool, other, 50)
+
+	require.Equal(t, []*proto.Transaction{other, parent, child}, pool.Transactions())
+
+	// The child cannot be selected without its parent.
+	require.Equal(t, []*proto.Transaction{other}, pool.Select(pb.Size(child), 10))
+
+	// Once the parent is mined the child stands on its own.
+	pool.Remove(parent)
+	require.Equal(t, []*proto.Transaction{other, child}, pool.Transactions())
+}
+
+func TestMempoolChildPaysForParent(t *testing.T) {
+	pool := NewMemPool(defaultMempoolBytes, 3, time.Hour)
+	parent := mempoolTx(nil)
+	child := mempoolTx(parent)
+	other := mempoolTx(nil)
+	addToMempool(t, pool, parent, 1)
+	addToMempool(t, pool, other, 50)
+	addToMempool(t, pool, child, 100)
+
+	// The package of the parent and the child pays more per byte than
+	// other on its own.
+	require.Equal(t, []*proto.Transaction{parent, child, other}, pool.Transactions())
+
+	// The child protects its parent from eviction as well.
+	_, err := pool.Add(mempoolTx(nil), 30)
+	require.ErrorIs(t, err, ErrMempoolFull)
+	tx := mempoolTx(nil)
+	addToMempool(t, pool, tx, 60)
+	require.Equal(t, []*proto.Transaction{tx, parent, child}, pool.Transactions())
+}
+
+// conflictingTx returns a transaction spending the same outputs as tx.
+func conflictingTx(tx *proto.Transaction) *proto.Transaction {
+	conflict := mempoolTx(nil)
+	conflict.Inputs = tx.Inputs
+	return conflict
+}
+
+func TestMempoolReplaceByFee(t *testing.T) {
+	pool := NewMemPool(defaultMempoolBytes, defaultMempoolTxs, time.Hour)
+	final := mempoolTx(nil)
+	addToMempool(t, pool, final, 10)
+	_, err := pool.Add(conflictingTx(final), 1000)
+	require.ErrorIs(t, err, ErrMempoolConflict)
+
+	original := mempoolTx(nil)
+	original.Replaceable = true
+	child := mempoolTx(original)
+	addToMempool(t, pool, original, 10)
+	addToMempool(t, pool, child, 10)
+
+	// The replacement pays for the transactions it evicts and for its
+	// own relay.
+	replacement := conflictingTx(original)
+	required := 20 + int64(minReplacementFeeRate*pb.Size(replacement))
+	_, err = pool.Add(replacement, required-1)
+	require.ErrorIs(t, err, ErrReplacementRejected)
+	require.True(t, pool.Has(original))
+
+	// A replacement cannot depend on what it replaces.
+	spendsChild := mempoolTx(child)
+	spendsChild.Inputs = append(spendsChild.Inputs, original.Inputs...)
+	_, err = pool.Add(spendsChild, 1000)
+	require.ErrorIs(t, err, ErrReplacementRejected)
+
+	addToMempool(t, pool, replacement, required)
+	require.False(t, pool.Has(original))
+	require.False(t, pool.Has(child))
+	require.Equal(t, []*proto.Transaction{replacement, final}, pool.Transactions())
+}
+
+func TestMempoolLimitsReplacements(t *testing.T) {
+	pool := NewMemPool(defaultMempoolBytes, defaultMempoolTxs, time.Hour)
+	original := mempoolTx(nil)
+	original.Replaceable = true
+	addToMempool(t, pool, original, 1)
+	parent := original
+	for i := 0; i < maxReplacements; i++ {
+		child := mempoolTx(parent)
+		addToMempool(t, pool, child, 1)
+		parent = child
+	}
+
+	_, err := pool.Add(conflictingTx(original), 1000000)
+	require.ErrorIs(t, err, ErrReplacementRejected)
+	require.Equal(t, maxReplacements+1, pool.Len())
+}
+
+func TestMempoolEvictsLowestFeeRate(t *testing.T) {
+	pool := NewMemPool(defaultMempoolBytes, 2, time.Hour)
+	low, high := mempoolTx(nil), mempoolTx(nil)
+	addToMempool(t, pool, low, 10)
+	addToMempool(t, pool, high, 30)
+
+	_, err := pool.Add(mempoolTx(nil), 5)
+	require.ErrorIs(t, err, ErrMempoolFull)
+	_, err = pool.Add(mempoolTx(nil), 10)
+	require.ErrorIs(t, err, ErrMempoolFull)
+
+	mid := mempoolTx(nil)
+	addToMempool(t, pool, mid, 20)
+	require.Equal(t, []*proto.Transaction{high, mid}, pool.Transactions())
+}
+
+func TestMempoolEvictsDescendants(t *testing.T) {
+	parent := mempoolTx(nil)
+	child := mempoolTx(parent)
+	pool := NewMemPool(pb.Size(parent)+pb.Size(child), defaultMempoolTxs, time.Hour)
+	addToMempool(t, pool, parent, 1)
+	addToMempool(t, pool, child, 2)
+
+	// A transaction depending on the cheap parent cannot evict it.
+	_, err := pool.Add(mempoolTx(child), 1000)
+	require.ErrorIs(t, err, ErrMempoolFull)
+
+	tx := mempoolTx(nil)
+	addToMempool(t, pool, tx, 1000)
+	require.Equal(t, []*proto.Transaction{tx}, pool.Transactions())
+	require.False(t, pool.Has(child))
+
+	// Transactions larger than the whole pool are refused.
+	large := mempoolTx(nil)
+	for i := 0; i < 10; i++ {
+		large.Outputs = append(large.Outputs, large.Outputs[0])
+	}
+	_, err = pool.Add(large, 1000000)
+	require.ErrorIs(t, err, ErrMempoolFull)
+}
+
+func TestMempoolExpiresTransactions(t *testing.T) {
+	pool := NewMemPool(defaultMempoolBytes, defaultMempoolTxs, 50*time.Millisecond)
+	parent := mempoolTx(nil)
+	addToMempool(t, pool, parent, 10)
+	time.Sleep(30 * time.Millisecond)
+	child := mempoolTx(parent)
+	addToMempool(t, pool, child, 10)
+
+	// The child goes with its expired parent.
+	time.Sleep(30 * time.Millisecond)
+	require.Empty(t, pool.Transactions())
+	require.Equal(t, 0, pool.Len())
+	require.Equal(t, 0, pool.Size())
+}
+
+func TestMempoolDump(t *testing.T) {
+	path := filepath.Join(t.TempDir(), mempoolFile)
+	dumped, err := readMempoolDump(path)
+	require.Nil(t, err)
+	require.Empty(t, dumped)
+
+	pool := NewMemPool(defaultMempoolBytes, defaultMempoolTxs, time.Hour)
+	parent := mempoolTx(nil)
+	child := mempoolTx(parent)
+	addToMempool(t, pool, parent, 1)
+	addToMempool(t, pool, child, 100)
+	require.Nil(t, pool.Dump(path))
+
+	dumped, err = readMempoolDump(path)
+	require.Nil(t, err)
+	require.Len(t, dumped, 2)
+	require.True(t, pb.Equal(parent, dumped[0].tx))
+	require.True(t, pb.Equal(child, dumped[1].tx))
+	require.Equal(t, pool.txx[hex.EncodeToString(types.HashTransaction(parent))].added.UnixNano(), dumped[0].added.UnixNano())
+
+	// A damaged dump is reported rather than partially loaded.
+	buf, err := os.ReadFile(path)
+	require.Nil(t, err)
+	require.Nil(t, os.WriteFile(path, buf[:len(buf)-1], 0o644))
+	_, err = readMempoolDump(path)
+	require.NotNil(t, err)
+
+	// So is a record size that wraps around once the timestamp is added.
+	huge := binary.AppendUvarint([]byte{mempoolDumpVersion}, math.MaxUint64-7)
+	require.Nil(t, os.WriteFile(path, append(huge, make([]byte, 16)...), 0o644))
+	_, err = readMempoolDump(path)
+	require.NotNil(t, err)
+}
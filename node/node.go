@@ -1,78 +1,75 @@
 package node
 
 import (
+	"bytes"
 	"context"
 	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
 	"net"
+	"os"
+	"path/filepath"
 	"sync"
 	"time"
 
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/peer"
 
 	"github.com/DenisBytes/GoChain/crypto"
+	"github.com/DenisBytes/GoChain/errs"
 	"github.com/DenisBytes/GoChain/proto"
 	"github.com/DenisBytes/GoChain/types"
 )
 
 const (
-	blockTime = time.Second * 5
+	blockTime      = time.Second * 5
+	maxKnownBlocks = 1024
+	listenAddrKey  = "listen-addr"
 )
 
-type Mempool struct {
-	txx  map[string]*proto.Transaction
-	lock sync.RWMutex
+// knownHashes remembers a bounded number of hashes, forgetting the
+// oldest one once the limit is reached.
+type knownHashes struct {
+	lock   sync.Mutex
+	hashes map[string]struct{}
+	order  []string
+	limit  int
 }
 
-func NewMemPool() *Mempool {
-	return &Mempool{
-		txx: make(map[string]*proto.Transaction),
+func newKnownHashes(limit int) *knownHashes {
+	return &knownHashes{
+		hashes: make(map[string]struct{}),
+		limit:  limit,
 	}
 }
 
-func (pool *Mempool) Clear() []*proto.Transaction {
-	pool.lock.Lock()
-	defer pool.lock.Unlock()
+// Has reports whether hash is known.
+func (k *knownHashes) Has(hash string) bool {
+	k.lock.Lock()
+	defer k.lock.Unlock()
 
-	txx := make([]*proto.Transaction, len(pool.txx))
-	it := 0
-	for k, v := range pool.txx {
-		delete(pool.txx, k)
-		txx[it] = v
-		it++
-	}
-	return txx
-}
-
-func (pool *Mempool) Len() int {
-	pool.lock.RLock()
-	defer pool.lock.RUnlock()
-
-	return len(pool.txx)
-}
-
-func (pool *Mempool) Has(tx *proto.Transaction) bool {
-	pool.lock.RLock()
-	defer pool.lock.RUnlock()
-
-	hash := hex.EncodeToString(types.HashTransaction(tx))
-	_, ok := pool.txx[hash]
+	_, ok := k.hashes[hash]
 	return ok
 }
 
-func (pool *Mempool) Add(tx *proto.Transaction) bool {
-	if pool.Has(tx) {
+// Add reports whether hash was not known yet.
+func (k *knownHashes) Add(hash string) bool {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	if _, ok := k.hashes[hash]; ok {
 		return false
 	}
-
-	pool.lock.Lock()
-	defer pool.lock.Unlock()
-
-	hash := hex.EncodeToString(types.HashTransaction(tx))
-	pool.txx[hash] = tx
-
+	if len(k.order) >= k.limit {
+		delete(k.hashes, k.order[0])
+		k.order = k.order[1:]
+	}
+	k.hashes[hash] = struct{}{}
+	k.order = append(k.order, hash)
 	return true
 }
 
@@ -80,6 +77,21 @@ type ServerConfig struct {
 	Version    string
 	ListenAddr string
 	PrivateKy  *crypto.PrivateKey
+	// DataDir is where the chain is persisted. When empty the chain is
+	// only kept in memory.
+	DataDir string
+	// Genesis is the specification of the network to join, DevGenesis
+	// when nil.
+	Genesis *Genesis
+	// MempoolBytes, MempoolTxs and MempoolTTL bound the size of the
+	// mempool, the number of transactions it holds and the time they
+	// may wait to be mined. Zero values use the defaults.
+	MempoolBytes int
+	MempoolTxs   int
+	MempoolTTL   time.Duration
+	// RequestTimeout bounds the time a peer may take to answer a request
+	// of the node, defaultRequestTimeout when zero.
+	RequestTimeout time.Duration
 }
 
 type Node struct {
@@ -89,47 +101,164 @@ type Node struct {
 	peers    map[proto.NodeClient]*proto.Version
 	peerLock sync.RWMutex
 	mempool  *Mempool
+	chain    *Chain
+
+	knownBlocks *knownHashes
+	orphanTxs   *orphanTxPool
+
+	// db is the database the chain is persisted in, nil when the node
+	// runs in memory.
+	db       *DB
+	server   *grpc.Server
+	quit     chan struct{}
+	stopOnce sync.Once
+	// loops are the goroutines Stop waits for before closing db, see
+	// spawn.
+	loops    sync.WaitGroup
+	loopLock sync.Mutex
+
+	syncLock   sync.RWMutex
+	syncing    bool
+	syncTarget int
 
 	proto.UnimplementedNodeServer
 }
 
-func NewNode(cfg ServerConfig) *Node {
+func NewNode(cfg ServerConfig) (*Node, error) {
 	loggerConfig := zap.NewProductionConfig()
 	loggerConfig.EncoderConfig.TimeKey = ""
 	logger, _ := loggerConfig.Build()
-	return &Node{
+
+	chain, db, err := openChain(cfg.DataDir, cfg.Genesis)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.MempoolBytes == 0 {
+		cfg.MempoolBytes = defaultMempoolBytes
+	}
+	if cfg.MempoolTxs == 0 {
+		cfg.MempoolTxs = defaultMempoolTxs
+	}
+	if cfg.MempoolTTL == 0 {
+		cfg.MempoolTTL = defaultMempoolTTL
+	}
+	if cfg.RequestTimeout == 0 {
+		cfg.RequestTimeout = defaultRequestTimeout
+	}
+
+	n := &Node{
 		peers:        make(map[proto.NodeClient]*proto.Version),
 		logger:       logger.Sugar(),
-		mempool:      NewMemPool(),
+		mempool:      NewMemPool(cfg.MempoolBytes, cfg.MempoolTxs, cfg.MempoolTTL),
+		chain:        chain,
+		knownBlocks:  newKnownHashes(maxKnownBlocks),
+		orphanTxs:    newOrphanTxPool(maxOrphanTxs, orphanTxTTL),
+		db:           db,
+		server:       grpc.NewServer(),
+		quit:         make(chan struct{}),
 		ServerConfig: cfg,
 	}
+	proto.RegisterNodeServer(n.server, n)
+	chain.Subscribe(n.onChainEvent)
+	if cfg.DataDir != "" {
+		n.loadMempool()
+	}
+	return n, nil
+}
+
+func openChain(dataDir string, genesis *Genesis) (*Chain, *DB, error) {
+	opts := []ChainOption{}
+	if genesis != nil {
+		opts = append(opts, WithGenesis(genesis))
+	}
+	if dataDir == "" {
+		chain, err := OpenChain(NewMemoryBlockStore(), NewMemoryTXStore(), NewMemoryUTXOStore(), opts...)
+		return chain, nil, err
+	}
+	db, err := OpenDB(dataDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	opts = append(opts, WithBatchWriter(db))
+	chain, err := OpenChain(NewDiskBlockStore(db), NewDiskTXStore(db), NewDiskUTXOStore(db), opts...)
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	return chain, db, nil
+}
+
+// loadMempool admits again the transactions saved on the last shutdown.
+// They are validated against the current tip, which may have moved since
+// they were saved.
+func (n *Node) loadMempool() {
+	path := filepath.Join(n.DataDir, mempoolFile)
+	txx, err := readMempoolDump(path)
+	if err != nil {
+		n.logger.Errorw("failed to load mempool", "err", err, "we", n.ListenAddr)
+		return
+	}
+	loaded := 0
+	for _, dumped := range txx {
+		fee, err := n.chain.TransactionFee(dumped.tx, n.mempool.Ancestors(dumped.tx)...)
+		if err != nil {
+			continue
+		}
+		if added, err := n.mempool.add(dumped.tx, fee, dumped.added); err == nil && added {
+			loaded++
+		}
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		n.logger.Errorw("failed to remove mempool dump", "err", err, "we", n.ListenAddr)
+	}
+	if len(txx) > 0 {
+		n.logger.Infow("loaded mempool", "loaded", loaded, "dropped", len(txx)-loaded, "we", n.ListenAddr)
+	}
+}
+
+// Stop shuts the node down: it stops producing blocks and serving peers,
+// saves the mempool to the data directory so it survives the restart and
+// closes the database.
+func (n *Node) Stop() error {
+	n.stopOnce.Do(func() {
+		n.loopLock.Lock()
+		close(n.quit)
+		n.loopLock.Unlock()
+	})
+	n.server.GracefulStop()
+	n.loops.Wait()
+
+	if n.db == nil {
+		return nil
+	}
+	if err := n.mempool.Dump(filepath.Join(n.DataDir, mempoolFile)); err != nil {
+		n.db.Close()
+		return err
+	}
+	n.logger.Infow("saved mempool", "transactions", n.mempool.Len(), "we", n.ListenAddr)
+	return n.db.Close()
 }
 
 // Receive Dial
 func (n *Node) Start(listenAddr string, boostrapNodes []string) error {
 	n.ListenAddr = listenAddr
 
-	opts := []grpc.ServerOption{}
-	grpcServer := grpc.NewServer(opts...)
-
 	ln, err := net.Listen("tcp", listenAddr)
 	if err != nil {
 		return err
 	}
 
-	proto.RegisterNodeServer(grpcServer, n)
-
 	n.logger.Infow("node started", "port", n.ListenAddr)
 
 	if len(boostrapNodes) > 0 {
-		go n.bootstrapNetwork(boostrapNodes)
+		n.spawn(func() { n.bootstrapNetwork(boostrapNodes) })
 	}
 
 	if n.PrivateKy != nil {
-		go n.validatorLoop()
+		n.spawn(n.validatorLoop)
 	}
 
-	return grpcServer.Serve(ln)
+	return n.server.Serve(ln)
 }
 
 // Dial
@@ -142,9 +271,12 @@ func makeNodeCient(listenAddr string) (proto.NodeClient, error) {
 }
 
 func (n *Node) Handshake(ctx context.Context, v *proto.Version) (*proto.Version, error) {
+	if err := n.checkChainID(v); err != nil {
+		return nil, statusError(err)
+	}
 	c, err := makeNodeCient(v.ListenAddr)
 	if err != nil {
-		return nil, err
+		return nil, statusError(fmt.Errorf("%w: %v", errs.ErrInvalidRequest, err))
 	}
 
 	n.addPeer(c, v)
@@ -152,45 +284,425 @@ func (n *Node) Handshake(ctx context.Context, v *proto.Version) (*proto.Version,
 	return n.getVersion(), nil
 }
 
+// HandleTransaction validates tx against the chain and the mempool and
+// relays it to the peers once admitted. Refused transactions are never
+// relayed, the reason is returned in the response.
 func (n *Node) HandleTransaction(ctx context.Context, tx *proto.Transaction) (*proto.Acquired, error) {
-	peer, _ := peer.FromContext(ctx)
+	from := ""
+	if peer, ok := peer.FromContext(ctx); ok {
+		from = peer.Addr.String()
+	}
+	return n.processTransaction(tx, from), nil
+}
+
+// processTransaction admits tx into the mempool, or keeps it as an orphan
+// when it spends outputs of transactions we have not seen yet.
+func (n *Node) processTransaction(tx *proto.Transaction, from string) *proto.Acquired {
 	hash := hex.EncodeToString(types.HashTransaction(tx))
 
-	if n.mempool.Add(tx) {
-		n.logger.Infow("received tx", "from", peer.Addr, "hash", hash, "we", n.ListenAddr)
+	fee, err := n.chain.TransactionFee(tx, n.mempool.Ancestors(tx)...)
+	if errors.Is(err, errs.ErrMissingInput) {
+		if missing := n.missingParents(tx); len(missing) > 0 {
+			if err := checkTransaction(tx); err != nil {
+				return n.rejectTransaction(hash, err)
+			}
+			n.orphanTxs.Add(hash, tx, missing)
+			n.logger.Infow("received orphan tx", "from", from, "hash", hash, "missing", len(missing), "we", n.ListenAddr)
+			return &proto.Acquired{
+				Reject:  proto.RejectReason_ORPHAN,
+				Message: fmt.Sprintf("waiting for %d parent transactions", len(missing)),
+			}
+		}
+	}
+	if err != nil {
+		return n.rejectTransaction(hash, err)
+	}
+	added, err := n.mempool.Add(tx, fee)
+	if err != nil {
+		return n.rejectTransaction(hash, err)
+	}
+	if added {
+		n.logger.Infow("received tx", "from", from, "hash", hash, "we", n.ListenAddr)
 		go func() {
 			if err := n.broadcast(tx); err != nil {
 				n.logger.Errorw("broadcast error", "err", err)
 			}
 		}()
+		n.promoteOrphans(tx)
+	}
+
+	return &proto.Acquired{}
+}
+
+// onChainEvent keeps the mempool in line with the main branch. The
+// transactions of disconnected blocks that are still valid go back to the
+// mempool, while the ones of connected blocks and the ones conflicting
+// with them leave it.
+func (n *Node) onChainEvent(ev ChainEvent) {
+	for _, b := range ev.Connected {
+		n.mempool.RemoveConfirmed(b.Transactions...)
 	}
 
+	// Disconnected blocks are given tip first, their transactions are
+	// added back oldest first so parents come before their children.
+	resurrected := 0
+	for i := len(ev.Disconnected) - 1; i >= 0; i-- {
+		for _, tx := range ev.Disconnected[i].Transactions {
+			if types.IsCoinbase(tx) {
+				continue
+			}
+			fee, err := n.chain.TransactionFee(tx, n.mempool.Ancestors(tx)...)
+			if err != nil {
+				continue
+			}
+			if added, err := n.mempool.Add(tx, fee); err == nil && added {
+				resurrected++
+			}
+		}
+	}
+	if len(ev.Disconnected) > 0 {
+		n.logger.Infow("reorganised mempool",
+			"disconnected", len(ev.Disconnected),
+			"connected", len(ev.Connected),
+			"resurrected tx", resurrected,
+			"we", n.ListenAddr)
+	}
+
+	for _, b := range ev.Connected {
+		n.promoteOrphans(b.Transactions...)
+	}
+}
+
+// missingParents returns the hashes of the transactions tx spends from
+// that are neither in the mempool nor in the chain.
+func (n *Node) missingParents(tx *proto.Transaction) []string {
+	var (
+		missing = []string{}
+		seen    = make(map[string]bool)
+	)
+	for _, input := range tx.Inputs {
+		parent := hex.EncodeToString(input.PrevTxHash)
+		if seen[parent] {
+			continue
+		}
+		seen[parent] = true
+		if !n.mempool.HasHash(parent) && !n.chain.HasTransaction(input.PrevTxHash) {
+			missing = append(missing, parent)
+		}
+	}
+	return missing
+}
+
+// promoteOrphans processes again the orphans spending the outputs of the
+// given transactions, which were just admitted or mined.
+func (n *Node) promoteOrphans(parents ...*proto.Transaction) {
+	for _, parent := range parents {
+		hash := hex.EncodeToString(types.HashTransaction(parent))
+		for _, orphan := range n.orphanTxs.TakeChildren(hash) {
+			n.processTransaction(orphan, "")
+		}
+	}
+}
+
+func (n *Node) rejectTransaction(hash string, err error) *proto.Acquired {
+	reason := rejectReason(err)
+	n.logger.Infow("rejected tx", "hash", hash, "reason", reason, "err", err, "we", n.ListenAddr)
+	return &proto.Acquired{
+		Reject:  reason,
+		Message: err.Error(),
+	}
+}
+
+// rejectReason maps a validation error to the reason sent to the peer.
+func rejectReason(err error) proto.RejectReason {
+	switch {
+	case errors.Is(err, errs.ErrInvalidSignature):
+		return proto.RejectReason_INVALID_SIGNATURE
+	case errors.Is(err, errs.ErrMissingInput):
+		return proto.RejectReason_MISSING_INPUTS
+	case errors.Is(err, errs.ErrDoubleSpend), errors.Is(err, ErrMempoolConflict):
+		return proto.RejectReason_DOUBLE_SPEND
+	case errors.Is(err, errs.ErrInsufficientFunds):
+		return proto.RejectReason_INSUFFICIENT_FUNDS
+	case errors.Is(err, errs.ErrImmatureCoinbase):
+		return proto.RejectReason_IMMATURE_COINBASE
+	case errors.Is(err, errs.ErrNotOwner):
+		return proto.RejectReason_NOT_OWNER
+	case errors.Is(err, errs.ErrTxTooLarge):
+		return proto.RejectReason_TOO_LARGE
+	case errors.Is(err, ErrMempoolFull):
+		return proto.RejectReason_MEMPOOL_FULL
+	case errors.Is(err, ErrReplacementRejected):
+		return proto.RejectReason_REPLACEMENT_REJECTED
+	default:
+		return proto.RejectReason_MALFORMED
+	}
+}
+
+// HandleBlock adds a block received from a peer and relays it. The hash
+// of a block only covers its header, so it is remembered once the block
+// is accepted: a copy with a broken signature, or a block refused for a
+// reason that may not last, must not stop the genuine block later. Nor
+// is an orphan remembered, it may leave the orphan pool before its
+// parent arrives and then has to be taken again.
+func (n *Node) HandleBlock(ctx context.Context, b *proto.Block) (*proto.Acquired, error) {
+	hash := hex.EncodeToString(types.HashBlock(b))
+	if n.knownBlocks.Has(hash) {
+		return &proto.Acquired{}, nil
+	}
+
+	peer := n.peerFromContext(ctx)
+	err := n.chain.AddBlock(b)
+	if errors.Is(err, errs.ErrDuplicateBlock) {
+		n.knownBlocks.Add(hash)
+		return &proto.Acquired{}, nil
+	}
+	if errors.Is(err, ErrOrphanBlock) {
+		n.logger.Infow("received orphan block", "hash", hash, "height", b.Header.Height, "we", n.ListenAddr)
+		if peer != nil {
+			n.updatePeerHeight(peer, b.Header.Height)
+			// A few missing blocks are asked for right away, a longer
+			// gap is left to a new sync.
+			if int(b.Header.Height)-n.chain.Height() > maxBlocksPerRequest {
+				n.spawn(n.syncChain)
+			} else {
+				n.spawn(func() { n.requestAncestors(peer, b) })
+			}
+		}
+		return &proto.Acquired{}, nil
+	}
+	if err != nil {
+		n.logger.Errorw("rejected block", "hash", hash, "err", err, "we", n.ListenAddr)
+		return nil, statusError(err)
+	}
+	if peer != nil {
+		n.updatePeerHeight(peer, b.Header.Height)
+	}
+	if !n.knownBlocks.Add(hash) {
+		// Another copy got in concurrently and was relayed.
+		return &proto.Acquired{}, nil
+	}
+	n.logger.Infow("received block", "hash", hash, "height", b.Header.Height, "we", n.ListenAddr)
+
+	go func() {
+		if err := n.broadcast(b); err != nil {
+			n.logger.Errorw("broadcast error", "err", err)
+		}
+	}()
+
 	return &proto.Acquired{}, nil
 }
 
+// requestAncestors asks peer for the blocks between our tip and the
+// orphan so the orphan can be connected.
+func (n *Node) requestAncestors(peer proto.NodeClient, orphan *proto.Block) {
+	from, to := n.chain.Height()+1, int(orphan.Header.Height)-1
+	for from <= to && !n.stopping() {
+		ctx, cancel := n.requestContext()
+		resp, err := peer.GetBlocks(ctx, &proto.RangeRequest{
+			From: int32(from),
+			To:   int32(to),
+		})
+		cancel()
+		if err != nil {
+			n.logger.Errorw("failed to request missing blocks", "err", err, "we", n.ListenAddr)
+			return
+		}
+		if len(resp.Blocks) == 0 {
+			return
+		}
+		for _, block := range resp.Blocks {
+			err := n.chain.AddBlock(block)
+			if errors.Is(err, ErrOrphanBlock) {
+				continue
+			}
+			if err != nil {
+				n.logger.Errorw("rejected missing block", "err", err, "we", n.ListenAddr)
+				return
+			}
+			n.knownBlocks.Add(hex.EncodeToString(types.HashBlock(block)))
+		}
+		from += len(resp.Blocks)
+	}
+}
+
+// GetMerkleProof returns the proof that a transaction is included in a
+// block, for clients that only keep the headers.
+func (n *Node) GetMerkleProof(ctx context.Context, req *proto.ProofRequest) (*proto.MerkleProof, error) {
+	block, err := n.chain.GetBlockByHash(req.BlockHash)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	proof, err := types.BuildProof(block, req.TxHash)
+	return proof, statusError(err)
+}
+
+// broadcast sends msg to every peer. A peer that is down or refuses msg
+// does not keep it from the others, the errors of all the peers are
+// returned together.
 func (n *Node) broadcast(msg any) error {
-	for peer := range n.peers {
+	var errList []error
+	for _, peer := range n.getPeers() {
+		var err error
 		switch v := msg.(type) {
 		case *proto.Transaction:
-			_, err := peer.HandleTransaction(context.Background(), v)
-			if err != nil {
-				return err
-			}
+			_, err = peer.HandleTransaction(n.outgoingContext(), v)
+		case *proto.Block:
+			_, err = peer.HandleBlock(n.outgoingContext(), v)
+		}
+		if err != nil {
+			errList = append(errList, err)
+		}
+	}
+	return errors.Join(errList...)
+}
+
+// outgoingContext tells the remote node which of its peers is calling,
+// since the address of the connection is not the one we listen on.
+func (n *Node) outgoingContext() context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), listenAddrKey, n.ListenAddr)
+}
+
+// peerFromContext returns the peer that made the call, if it is known.
+func (n *Node) peerFromContext(ctx context.Context) proto.NodeClient {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil
+	}
+	addrs := md.Get(listenAddrKey)
+	if len(addrs) == 0 {
+		return nil
+	}
+
+	n.peerLock.RLock()
+	defer n.peerLock.RUnlock()
+
+	for c, v := range n.peers {
+		if v.ListenAddr == addrs[0] {
+			return c
 		}
 	}
 	return nil
 }
 
 func (n *Node) validatorLoop() {
-
 	n.logger.Infow("starting validator loop", "pubkey", n.PrivateKy.Public(), "block time", blockTime)
 	ticker := time.NewTicker(blockTime)
+	defer ticker.Stop()
 	for {
-		<-ticker.C
+		select {
+		case <-ticker.C:
+		case <-n.quit:
+			return
+		}
+
+		block, err := n.proposeBlock()
+		if err != nil {
+			n.logger.Errorw("failed to propose block", "err", err, "we", n.ListenAddr)
+			continue
+		}
+		if block == nil {
+			continue
+		}
+		go func() {
+			if err := n.broadcast(block); err != nil {
+				n.logger.Errorw("broadcast error", "err", err)
+			}
+		}()
+	}
+}
 
-		txx := n.mempool.Clear()
-		n.logger.Infow("time to create a new block", "length tx", len(txx))
+// proposeBlock creates and adds the next block when it is our turn to
+// propose it, and returns nil otherwise. The validators take turns by
+// height, so they never build competing blocks; a validator that is down
+// stalls the chain at its height until it comes back.
+func (n *Node) proposeBlock() (*proto.Block, error) {
+	height := n.chain.Height() + 1
+	if !bytes.Equal(n.chain.Proposer(height).Bytes(), n.PrivateKy.Public().Bytes()) {
+		return nil, nil
+	}
 
+	block, rejected, err := n.createBlock()
+	if err != nil {
+		return nil, err
+	}
+	if err := n.chain.AddBlock(block); err != nil {
+		return nil, fmt.Errorf("add block at height %d: %w", block.Header.Height, err)
+	}
+	n.mempool.Remove(rejected...)
+
+	hash := hex.EncodeToString(types.HashBlock(block))
+	n.knownBlocks.Add(hash)
+	n.logger.Infow("created new block",
+		"we", n.ListenAddr,
+		"height", block.Header.Height,
+		"hash", hash,
+		"length tx", len(block.Transactions),
+		"rejected tx", len(rejected))
+	return block, nil
+}
+
+// createBlock assembles a block on top of the current tip out of a
+// coinbase paying the block reward and the fees to the validator and the
+// mempool transactions with the best fee rate that fit in a block, and
+// signs it with the validator key. Transactions that fail validation are
+// returned separately so they can be dropped from the mempool.
+func (n *Node) createBlock() (*proto.Block, []*proto.Transaction, error) {
+	height := n.chain.Height()
+	prevBlock, err := n.chain.GetBlockByHeight(height)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	candidates := n.mempool.Select(maxBlockSize-blockReservedSize, maxBlockTxs-1)
+	txx, rejected, fees := n.chain.ValidateTransactions(candidates)
+
+	// Once the reward is down to zero a block without fees has nothing
+	// to pay, its coinbase then only commits to the height.
+	outputs := []*proto.TxOutput{}
+	if amount := n.chain.BlockReward(height+1) + fees; amount > 0 {
+		outputs = append(outputs, &proto.TxOutput{
+			Amount:  amount,
+			Address: n.PrivateKy.Public().Address().Bytes(),
+		})
+	}
+	coinbase := types.NewCoinbaseTransaction(height+1, outputs...)
+	block := &proto.Block{
+		Header: &proto.Header{
+			Version:   blockVersion,
+			Height:    int32(height + 1),
+			PrevHash:  types.HashBlock(prevBlock),
+			Timestamp: time.Now().UnixNano(),
+		},
+		Transactions: append([]*proto.Transaction{coinbase}, txx...),
+	}
+	types.SignBlock(n.chain.ChainID(), n.PrivateKy, block)
+
+	return block, rejected, nil
+}
+
+// spawn runs f in a goroutine that Stop waits for, as f may use the
+// chain. Nothing is started once the node is stopping.
+func (n *Node) spawn(f func()) {
+	n.loopLock.Lock()
+	defer n.loopLock.Unlock()
+
+	if n.stopping() {
+		return
+	}
+	n.loops.Add(1)
+	go func() {
+		defer n.loops.Done()
+		f()
+	}()
+}
+
+func (n *Node) stopping() bool {
+	select {
+	case <-n.quit:
+		return true
+	default:
+		return false
 	}
 }
 
@@ -218,10 +730,21 @@ func (n *Node) dialRemoteNode(addr string) (proto.NodeClient, *proto.Version, er
 	if err != nil {
 		return nil, nil, err
 	}
+	if err := n.checkChainID(v); err != nil {
+		return nil, nil, err
+	}
 
 	return c, v, nil
 }
 
+// checkChainID refuses peers on another network.
+func (n *Node) checkChainID(v *proto.Version) error {
+	if v.ChainID != n.chain.ChainID() {
+		return fmt.Errorf("%w: %s is on %q, we are on %q", ErrWrongChain, v.ListenAddr, v.ChainID, n.chain.ChainID())
+	}
+	return nil
+}
+
 func (n *Node) addPeer(c proto.NodeClient, v *proto.Version) {
 	n.peerLock.Lock()
 	defer n.peerLock.Unlock()
@@ -229,12 +752,27 @@ func (n *Node) addPeer(c proto.NodeClient, v *proto.Version) {
 	n.peers[c] = v
 
 	if len(v.PeerList) > 0 {
-		go n.bootstrapNetwork(v.PeerList)
+		n.spawn(func() { n.bootstrapNetwork(v.PeerList) })
 	}
 	n.logger.Infow("new peer connected",
 		"we", n.ListenAddr,
 		"remoteNode", v.ListenAddr,
 		"height", v.Height)
+
+	if int(v.Height) > n.chain.Height() {
+		n.spawn(n.syncChain)
+	}
+}
+
+// updatePeerHeight raises the height we know c to have, as it only
+// tells it in the handshake.
+func (n *Node) updatePeerHeight(c proto.NodeClient, height int32) {
+	n.peerLock.Lock()
+	defer n.peerLock.Unlock()
+
+	if v, ok := n.peers[c]; ok && height > v.Height {
+		v.Height = height
+	}
 }
 
 func (n *Node) deletePeer(c proto.NodeClient) {
@@ -246,10 +784,22 @@ func (n *Node) deletePeer(c proto.NodeClient) {
 func (n *Node) getVersion() *proto.Version {
 	return &proto.Version{
 		Version:    "gochain-0.1",
-		Height:     0,
+		Height:     int32(n.chain.Height()),
 		ListenAddr: n.ListenAddr,
 		PeerList:   n.getPeerList(),
+		ChainID:    n.chain.ChainID(),
+	}
+}
+
+func (n *Node) getPeers() []proto.NodeClient {
+	n.peerLock.RLock()
+	defer n.peerLock.RUnlock()
+
+	peers := make([]proto.NodeClient, 0, len(n.peers))
+	for c := range n.peers {
+		peers = append(peers, c)
 	}
+	return peers
 }
 
 func (n *Node) getPeerList() []string {
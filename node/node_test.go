@@ -0,0 +1,711 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/DenisBytes/GoChain/crypto"
+	"github.com/DenisBytes/GoChain/proto"
+	"github.com/DenisBytes/GoChain/types"
+	"github.com/DenisBytes/GoChain/util"
+)
+
+func TestCreateBlock(t *testing.T) {
+	n := newTestNode(t, ServerConfig{
+		Version:   "gochain-0.1",
+		PrivateKy: validatorKey(),
+	})
+	privKey := crypto.NewPrivateKeyFromString(godSeed)
+
+	genesis, err := n.chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+	tx := &proto.Transaction{
+		Version: 1,
+		Inputs: []*proto.TxInput{
+			{
+				PublicKey:    privKey.Public().Bytes(),
+				PrevTxHash:   types.HashTransaction(genesis.Transactions[0]),
+				PrevOutIndex: 0,
+			},
+		},
+		Outputs: []*proto.TxOutput{
+			{
+				Amount:  990,
+				Address: crypto.GeneratePrivateKey().Public().Address().Bytes(),
+			},
+		},
+	}
+	require.Nil(t, types.SignTransaction(devChainID, privKey, tx, genesis.Transactions[0].Outputs))
+
+	invalidTx := &proto.Transaction{
+		Version: 1,
+		Inputs: []*proto.TxInput{
+			{
+				PublicKey:    privKey.Public().Bytes(),
+				PrevTxHash:   util.RandomHash(),
+				PrevOutIndex: 0,
+			},
+		},
+	}
+
+	for _, tx := range []*proto.Transaction{tx, invalidTx} {
+		added, err := n.mempool.Add(tx, 0)
+		require.Nil(t, err)
+		require.True(t, added)
+	}
+
+	block, rejected, err := n.createBlock()
+	require.Nil(t, err)
+	require.Equal(t, int32(1), block.Header.Height)
+	require.Equal(t, types.HashBlock(genesis), block.Header.PrevHash)
+	require.Len(t, block.Transactions, 2)
+	require.True(t, types.IsCoinbase(block.Transactions[0]))
+	require.Equal(t, n.chain.BlockReward(1)+10, block.Transactions[0].Outputs[0].Amount)
+	require.Equal(t, n.PrivateKy.Public().Address().Bytes(), block.Transactions[0].Outputs[0].Address)
+	require.Equal(t, tx, block.Transactions[1])
+	require.Equal(t, []*proto.Transaction{invalidTx}, rejected)
+
+	require.Nil(t, n.chain.AddBlock(block))
+	require.Equal(t, 1, n.chain.Height())
+	require.Equal(t, 1000+n.chain.BlockReward(1), n.chain.TotalSupply())
+}
+
+func TestCreateBlockWithoutReward(t *testing.T) {
+	g := DevGenesis()
+	g.Params.BlockReward = RewardParams{}
+	n := newTestNode(t, ServerConfig{PrivateKy: validatorKey(), Genesis: g})
+
+	block, err := n.proposeBlock()
+	require.Nil(t, err)
+	require.Empty(t, block.Transactions[0].Outputs)
+	require.Equal(t, 1, n.chain.Height())
+
+	// Fees are still paid.
+	genesis, err := n.chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+	godKey := crypto.NewPrivateKeyFromString(godSeed)
+	addToMempool(t, n.mempool, spendOutput(godKey, genesis.Transactions[0], 0, 990), 10)
+	block, err = n.proposeBlock()
+	require.Nil(t, err)
+	require.Len(t, block.Transactions[0].Outputs, 1)
+	require.Equal(t, int64(10), block.Transactions[0].Outputs[0].Amount)
+	require.Equal(t, int64(1000), n.chain.TotalSupply())
+}
+
+func TestCreateBlockIncludesMempoolChains(t *testing.T) {
+	n := newTestNode(t, ServerConfig{PrivateKy: validatorKey()})
+	godKey := crypto.NewPrivateKeyFromString(godSeed)
+
+	parent := spendGenesis(t, n.chain, 100)
+	child := &proto.Transaction{
+		Version: 1,
+		Inputs: []*proto.TxInput{
+			{
+				PublicKey:    godKey.Public().Bytes(),
+				PrevTxHash:   types.HashTransaction(parent),
+				PrevOutIndex: 1,
+			},
+		},
+		Outputs: []*proto.TxOutput{
+			{
+				Amount:  850,
+				Address: godKey.Public().Address().Bytes(),
+			},
+		},
+	}
+	require.Nil(t, types.SignTransaction(devChainID, godKey, child, parent.Outputs[1:]))
+	addToMempool(t, n.mempool, parent, 0)
+	addToMempool(t, n.mempool, child, 50)
+
+	block, rejected, err := n.createBlock()
+	require.Nil(t, err)
+	require.Empty(t, rejected)
+	require.Equal(t, []*proto.Transaction{parent, child}, block.Transactions[1:])
+	require.Equal(t, n.chain.BlockReward(1)+50, block.Transactions[0].Outputs[0].Amount)
+	require.Nil(t, n.chain.AddBlock(block))
+}
+
+func TestHandleBlock(t *testing.T) {
+	var (
+		validator = newTestNode(t, ServerConfig{PrivateKy: validatorKey()})
+		n         = newTestNode(t, ServerConfig{})
+	)
+
+	block, _, err := validator.createBlock()
+	require.Nil(t, err)
+	require.Nil(t, validator.chain.AddBlock(block))
+
+	_, err = n.HandleBlock(context.Background(), block)
+	require.Nil(t, err)
+	require.Equal(t, 1, n.chain.Height())
+	require.True(t, n.chain.HasBlock(types.HashBlock(block)))
+
+	// Already seen blocks are acknowledged without being processed again.
+	_, err = n.HandleBlock(context.Background(), block)
+	require.Nil(t, err)
+	require.Equal(t, 1, n.chain.Height())
+
+	invalid, _, err := validator.createBlock()
+	require.Nil(t, err)
+	invalid.Signature = util.RandomHash()
+	_, err = n.HandleBlock(context.Background(), invalid)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	require.Equal(t, 1, n.chain.Height())
+
+	// Malformed blocks are refused without bringing the node down.
+	_, err = n.HandleBlock(context.Background(), &proto.Block{Transactions: block.Transactions})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	malformed, _, err := validator.createBlock()
+	require.Nil(t, err)
+	malformed.PublicKey = malformed.PublicKey[:10]
+	_, err = n.HandleBlock(context.Background(), malformed)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestHandleBlockAfterTamperedCopy(t *testing.T) {
+	var (
+		validator = newTestNode(t, ServerConfig{PrivateKy: validatorKey()})
+		n         = newTestNode(t, ServerConfig{})
+	)
+	block, _, err := validator.createBlock()
+	require.Nil(t, err)
+	require.Nil(t, validator.chain.AddBlock(block))
+
+	// A copy with another signature has the same hash, it must not make
+	// the genuine block look already seen.
+	tampered := &proto.Block{
+		Header:       block.Header,
+		Transactions: block.Transactions,
+		PublicKey:    block.PublicKey,
+		Signature:    append(util.RandomHash(), util.RandomHash()...),
+	}
+	require.Equal(t, types.HashBlock(block), types.HashBlock(tampered))
+	_, err = n.HandleBlock(context.Background(), tampered)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	require.Equal(t, 0, n.chain.Height())
+
+	_, err = n.HandleBlock(context.Background(), block)
+	require.Nil(t, err)
+	require.Equal(t, 1, n.chain.Height())
+}
+
+func TestHandleBlockAfterOrphanDropped(t *testing.T) {
+	var (
+		validator = newTestNode(t, ServerConfig{PrivateKy: validatorKey()})
+		n         = newTestNode(t, ServerConfig{})
+	)
+	blocks := produceBlocks(t, validator, 2)
+
+	_, err := n.HandleBlock(context.Background(), blocks[1])
+	require.Nil(t, err)
+	require.Equal(t, 1, n.chain.OrphanCount())
+
+	// The orphan expires before its parent comes, a later copy must be
+	// taken again.
+	n.chain.lock.Lock()
+	n.chain.orphans.remove(n.chain.orphans.oldest())
+	n.chain.lock.Unlock()
+	_, err = n.HandleBlock(context.Background(), blocks[1])
+	require.Nil(t, err)
+	require.Equal(t, 1, n.chain.OrphanCount())
+
+	_, err = n.HandleBlock(context.Background(), blocks[0])
+	require.Nil(t, err)
+	require.Equal(t, 2, n.chain.Height())
+}
+
+func TestBroadcastPastFailingPeer(t *testing.T) {
+	var (
+		validator = newTestNode(t, ServerConfig{PrivateKy: validatorKey()})
+		peer      = newTestNode(t, ServerConfig{})
+	)
+	validator.peers[downClient{addr: ":4000"}] = &proto.Version{ListenAddr: ":4000"}
+	validator.peers[localClient{peer}] = peer.getVersion()
+	validator.peers[downClient{addr: ":6000"}] = &proto.Version{ListenAddr: ":6000"}
+
+	block, _, err := validator.createBlock()
+	require.Nil(t, err)
+	require.Nil(t, validator.chain.AddBlock(block))
+	require.ErrorIs(t, validator.broadcast(block), errPeerDown)
+	require.Equal(t, 1, peer.chain.Height())
+}
+
+func TestGetMerkleProof(t *testing.T) {
+	n := newTestNode(t, ServerConfig{PrivateKy: validatorKey()})
+	tx := spendGenesis(t, n.chain, 100)
+	addToMempool(t, n.mempool, tx, 0)
+	block, _, err := n.createBlock()
+	require.Nil(t, err)
+	require.Nil(t, n.chain.AddBlock(block))
+
+	txHash := types.HashTransaction(tx)
+	proof, err := n.GetMerkleProof(context.Background(), &proto.ProofRequest{
+		BlockHash: types.HashBlock(block),
+		TxHash:    txHash,
+	})
+	require.Nil(t, err)
+	require.True(t, types.VerifyProof(block.Header.RootHash, txHash, proof))
+
+	_, err = n.GetMerkleProof(context.Background(), &proto.ProofRequest{
+		BlockHash: types.HashBlock(block),
+		TxHash:    util.RandomHash(),
+	})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = n.GetMerkleProof(context.Background(), &proto.ProofRequest{
+		BlockHash: util.RandomHash(),
+		TxHash:    txHash,
+	})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestHandleTransactionRejectsDoubleSpend(t *testing.T) {
+	n := newTestNode(t, ServerConfig{})
+	ctx := context.Background()
+
+	first := spendGenesis(t, n.chain, 100)
+	second := spendGenesis(t, n.chain, 200)
+
+	ack, err := n.HandleTransaction(ctx, first)
+	require.Nil(t, err)
+	require.Equal(t, proto.RejectReason_ACCEPTED, ack.Reject)
+	ack, err = n.HandleTransaction(ctx, second)
+	require.Nil(t, err)
+	require.Equal(t, proto.RejectReason_DOUBLE_SPEND, ack.Reject)
+	require.Equal(t, 1, n.mempool.Len())
+	require.False(t, n.mempool.Has(second))
+
+	_, err = n.mempool.Add(second, 0)
+	require.ErrorIs(t, err, ErrMempoolConflict)
+
+	// Receiving the same transaction again is not a conflict.
+	ack, err = n.HandleTransaction(ctx, first)
+	require.Nil(t, err)
+	require.Equal(t, proto.RejectReason_ACCEPTED, ack.Reject)
+
+	// Once the first spend leaves the pool its outpoint is free again.
+	n.mempool.Remove(first)
+	ack, err = n.HandleTransaction(ctx, second)
+	require.Nil(t, err)
+	require.Equal(t, proto.RejectReason_ACCEPTED, ack.Reject)
+	require.True(t, n.mempool.Has(second))
+}
+
+func TestHandleTransactionReplaceByFee(t *testing.T) {
+	var (
+		n      = newTestNode(t, ServerConfig{})
+		godKey = crypto.NewPrivateKeyFromString(godSeed)
+	)
+	genesis, err := n.chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+	spend := func(fee int64) *proto.Transaction {
+		tx := spendGenesis(t, n.chain, 100)
+		tx.Outputs[1].Amount -= fee
+		tx.Replaceable = true
+		require.Nil(t, types.SignTransaction(devChainID, godKey, tx, genesis.Transactions[0].Outputs))
+		return tx
+	}
+
+	original := spend(10)
+	ack, err := n.HandleTransaction(context.Background(), original)
+	require.Nil(t, err)
+	require.Equal(t, proto.RejectReason_ACCEPTED, ack.Reject)
+
+	ack, err = n.HandleTransaction(context.Background(), spend(11))
+	require.Nil(t, err)
+	require.Equal(t, proto.RejectReason_REPLACEMENT_REJECTED, ack.Reject)
+
+	replacement := spend(500)
+	ack, err = n.HandleTransaction(context.Background(), replacement)
+	require.Nil(t, err)
+	require.Equal(t, proto.RejectReason_ACCEPTED, ack.Reject)
+	require.Equal(t, []*proto.Transaction{replacement}, n.mempool.Transactions())
+}
+
+// spendOutput returns a transaction moving amount from the output of
+// parent at index, owned by privKey, back to privKey.
+func spendOutput(privKey *crypto.PrivateKey, parent *proto.Transaction, index uint32, amount int64) *proto.Transaction {
+	tx := &proto.Transaction{
+		Version: 1,
+		Inputs: []*proto.TxInput{
+			{
+				PublicKey:    privKey.Public().Bytes(),
+				PrevTxHash:   types.HashTransaction(parent),
+				PrevOutIndex: index,
+			},
+		},
+		Outputs: []*proto.TxOutput{
+			{
+				Amount:  amount,
+				Address: privKey.Public().Address().Bytes(),
+			},
+		},
+	}
+	if err := types.SignTransaction(devChainID, privKey, tx, parent.Outputs[index:index+1]); err != nil {
+		panic(err)
+	}
+	return tx
+}
+
+func TestHandleTransactionSpendsMempoolOutputs(t *testing.T) {
+	var (
+		n      = newTestNode(t, ServerConfig{})
+		godKey = crypto.NewPrivateKeyFromString(godSeed)
+		parent = spendGenesis(t, n.chain, 100)
+		child  = spendOutput(godKey, parent, 1, 800)
+	)
+
+	ack, err := n.HandleTransaction(context.Background(), parent)
+	require.Nil(t, err)
+	require.Equal(t, proto.RejectReason_ACCEPTED, ack.Reject)
+	ack, err = n.HandleTransaction(context.Background(), child)
+	require.Nil(t, err)
+	require.Equal(t, proto.RejectReason_ACCEPTED, ack.Reject)
+	require.Equal(t, []*proto.Transaction{parent, child}, n.mempool.Transactions())
+
+	// Mempool outputs are spendable once only.
+	ack, err = n.HandleTransaction(context.Background(), spendOutput(godKey, parent, 1, 700))
+	require.Nil(t, err)
+	require.Equal(t, proto.RejectReason_DOUBLE_SPEND, ack.Reject)
+}
+
+func TestOrphanTransactionPromotedWhenParentArrives(t *testing.T) {
+	var (
+		n          = newTestNode(t, ServerConfig{})
+		godKey     = crypto.NewPrivateKeyFromString(godSeed)
+		parent     = spendGenesis(t, n.chain, 100)
+		child      = spendOutput(godKey, parent, 1, 800)
+		grandchild = spendOutput(godKey, child, 0, 700)
+	)
+
+	for _, tx := range []*proto.Transaction{grandchild, child} {
+		ack, err := n.HandleTransaction(context.Background(), tx)
+		require.Nil(t, err)
+		require.Equal(t, proto.RejectReason_ORPHAN, ack.Reject)
+	}
+	require.Equal(t, 0, n.mempool.Len())
+	require.Equal(t, 2, n.orphanTxs.Len())
+
+	ack, err := n.HandleTransaction(context.Background(), parent)
+	require.Nil(t, err)
+	require.Equal(t, proto.RejectReason_ACCEPTED, ack.Reject)
+	require.Equal(t, 0, n.orphanTxs.Len())
+	require.Equal(t, []*proto.Transaction{parent, child, grandchild}, n.mempool.Transactions())
+}
+
+func TestOrphanTransactionPromotedWhenParentMined(t *testing.T) {
+	var (
+		validator = newTestNode(t, ServerConfig{PrivateKy: validatorKey()})
+		n         = newTestNode(t, ServerConfig{})
+		godKey    = crypto.NewPrivateKeyFromString(godSeed)
+		parent    = spendGenesis(t, n.chain, 100)
+		child     = spendOutput(godKey, parent, 1, 800)
+	)
+
+	ack, err := n.HandleTransaction(context.Background(), child)
+	require.Nil(t, err)
+	require.Equal(t, proto.RejectReason_ORPHAN, ack.Reject)
+
+	addToMempool(t, validator.mempool, parent, 0)
+	block, _, err := validator.createBlock()
+	require.Nil(t, err)
+	require.Nil(t, validator.chain.AddBlock(block))
+
+	_, err = n.HandleBlock(context.Background(), block)
+	require.Nil(t, err)
+	require.Equal(t, 0, n.orphanTxs.Len())
+	require.Equal(t, []*proto.Transaction{child}, n.mempool.Transactions())
+}
+
+func TestHandleTransactionRejectsInvalid(t *testing.T) {
+	var (
+		n      = newTestNode(t, ServerConfig{})
+		peer1  = newTestNode(t, ServerConfig{ListenAddr: "peer"})
+		ctx    = context.Background()
+		godKey = crypto.NewPrivateKeyFromString(godSeed)
+	)
+	n.addPeer(localClient{peer1}, &proto.Version{ListenAddr: "peer"})
+	genesis, err := n.chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+	spent := genesis.Transactions[0].Outputs
+
+	unsigned := spendGenesis(t, n.chain, 100)
+	unsigned.Inputs[0].Signature = nil
+
+	missing := spendGenesis(t, n.chain, 100)
+	missing.Inputs[0].PrevOutIndex = 5
+	require.Nil(t, types.SignTransaction(devChainID, godKey, missing, spent))
+
+	orphan := spendGenesis(t, n.chain, 100)
+	orphan.Inputs[0].PrevTxHash = util.RandomHash()
+	require.Nil(t, types.SignTransaction(devChainID, godKey, orphan, spent))
+
+	overspend := spendGenesis(t, n.chain, 100)
+	overspend.Outputs[1].Amount = 1000
+	require.Nil(t, types.SignTransaction(devChainID, godKey, overspend, spent))
+
+	thief := crypto.GeneratePrivateKey()
+	theft := spendGenesis(t, n.chain, 100)
+	theft.Inputs[0].PublicKey = thief.Public().Bytes()
+	require.Nil(t, types.SignTransaction(devChainID, thief, theft, spent))
+
+	noOutputs := spendGenesis(t, n.chain, 100)
+	noOutputs.Outputs = nil
+	require.Nil(t, types.SignTransaction(devChainID, godKey, noOutputs, spent))
+
+	huge := spendGenesis(t, n.chain, 100)
+	huge.Inputs[0].Signature = make([]byte, maxTxSize)
+
+	for reason, tx := range map[proto.RejectReason]*proto.Transaction{
+		proto.RejectReason_INVALID_SIGNATURE:  unsigned,
+		proto.RejectReason_MISSING_INPUTS:     missing,
+		proto.RejectReason_ORPHAN:             orphan,
+		proto.RejectReason_INSUFFICIENT_FUNDS: overspend,
+		proto.RejectReason_NOT_OWNER:          theft,
+		proto.RejectReason_MALFORMED:          noOutputs,
+		proto.RejectReason_TOO_LARGE:          huge,
+	} {
+		ack, err := n.HandleTransaction(ctx, tx)
+		require.Nil(t, err)
+		require.Equal(t, reason, ack.Reject)
+		require.NotEmpty(t, ack.Message)
+	}
+	require.Equal(t, 0, n.mempool.Len())
+	require.Equal(t, 1, n.orphanTxs.Len())
+
+	// Only the valid transaction reaches the peer.
+	_, err = n.HandleTransaction(ctx, spendGenesis(t, n.chain, 100))
+	require.Nil(t, err)
+	require.Eventually(t, func() bool { return peer1.mempool.Len() == 1 }, time.Second, 10*time.Millisecond)
+	require.Never(t, func() bool { return peer1.mempool.Len() > 1 }, 100*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestMempoolSurvivesRestart(t *testing.T) {
+	var (
+		dir    = t.TempDir()
+		n      = newTestNode(t, ServerConfig{DataDir: dir})
+		godKey = crypto.NewPrivateKeyFromString(godSeed)
+		parent = spendGenesis(t, n.chain, 100)
+		child  = spendOutput(godKey, parent, 1, 800)
+	)
+	for _, tx := range []*proto.Transaction{parent, child} {
+		ack, err := n.HandleTransaction(context.Background(), tx)
+		require.Nil(t, err)
+		require.Equal(t, proto.RejectReason_ACCEPTED, ack.Reject)
+	}
+	// Transactions are validated again on startup.
+	invalid := spendOutput(godKey, parent, 0, 10)
+	addToMempool(t, n.mempool, invalid, 0)
+	require.Nil(t, n.Stop())
+
+	n = newTestNode(t, ServerConfig{DataDir: dir})
+	require.Equal(t, []*proto.Transaction{parent, child}, n.mempool.Transactions())
+	require.Nil(t, n.Stop())
+}
+
+func TestStopWaitsForBackgroundWork(t *testing.T) {
+	var (
+		n       = newTestNode(t, ServerConfig{DataDir: t.TempDir()})
+		block   = randomBlock(t, n.chain)
+		release = make(chan struct{})
+		added   = make(chan error, 1)
+		stopped = make(chan error, 1)
+	)
+	n.spawn(func() {
+		<-release
+		added <- n.chain.AddBlock(block)
+	})
+	go func() { stopped <- n.Stop() }()
+	require.Never(t, func() bool { return len(stopped) > 0 }, 100*time.Millisecond, 10*time.Millisecond)
+	close(release)
+	require.Nil(t, <-added)
+	require.Nil(t, <-stopped)
+
+	// Nothing is started once the node is stopped.
+	n.spawn(func() { t.Error("spawned after Stop") })
+	_, err := n.db.Get(headKey)
+	require.ErrorIs(t, err, ErrDBClosed)
+}
+
+func TestMempoolFollowsChain(t *testing.T) {
+	var (
+		n       = newTestNode(t, ServerConfig{})
+		godKey  = crypto.NewPrivateKeyFromString(godSeed)
+		pending = spendGenesis(t, n.chain, 100)
+		child   = spendOutput(godKey, pending, 1, 800)
+		mined   = spendGenesis(t, n.chain, 200)
+		other   = spendOutput(godKey, mined, 1, 700)
+	)
+	genesis, err := n.chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+	for _, tx := range []*proto.Transaction{pending, child} {
+		ack, err := n.HandleTransaction(context.Background(), tx)
+		require.Nil(t, err)
+		require.Equal(t, proto.RejectReason_ACCEPTED, ack.Reject)
+	}
+
+	// The block confirms a spend conflicting with the pending one, which
+	// leaves the mempool with its child.
+	a1 := blockOn(genesis, mined)
+	_, err = n.HandleBlock(context.Background(), a1)
+	require.Nil(t, err)
+	require.Equal(t, 0, n.mempool.Len())
+
+	ack, err := n.HandleTransaction(context.Background(), other)
+	require.Nil(t, err)
+	require.Equal(t, proto.RejectReason_ACCEPTED, ack.Reject)
+
+	// A heavier branch without a1 gives its transaction back to the
+	// mempool, ahead of the child spending it.
+	b1 := blockOn(genesis)
+	b2 := blockOn(b1)
+	for _, b := range []*proto.Block{b1, b2} {
+		_, err := n.HandleBlock(context.Background(), b)
+		require.Nil(t, err)
+	}
+	requireTip(t, n.chain, b2)
+	require.Equal(t, []*proto.Transaction{mined, other}, n.mempool.Transactions())
+}
+
+func TestHandshakeRefusesOtherChain(t *testing.T) {
+	n := newTestNode(t, ServerConfig{Version: "gochain-0.1", ListenAddr: ":3000"})
+	same := newTestNode(t, ServerConfig{Version: "gochain-0.1", ListenAddr: ":4000"})
+	testnet := DevGenesis()
+	testnet.ChainID = "gochain-testnet"
+	other := newTestNode(t, ServerConfig{Version: "gochain-0.1", ListenAddr: ":5000", Genesis: testnet})
+
+	_, err := n.Handshake(context.Background(), other.getVersion())
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	require.Empty(t, n.getPeerList())
+
+	v, err := n.Handshake(context.Background(), same.getVersion())
+	require.Nil(t, err)
+	require.Equal(t, devChainID, v.ChainID)
+	require.Equal(t, []string{":4000"}, n.getPeerList())
+}
+
+func newTestNode(t *testing.T, cfg ServerConfig) *Node {
+	n, err := NewNode(cfg)
+	require.Nil(t, err)
+	return n
+}
+
+// localClient lets a node talk to another node in the same process
+// without going through gRPC.
+type localClient struct {
+	n *Node
+}
+
+var errPeerDown = errors.New("peer down")
+
+// downClient is a peer that fails every call.
+type downClient struct {
+	localClient
+	addr string
+}
+
+func (downClient) HandleBlock(context.Context, *proto.Block, ...grpc.CallOption) (*proto.Acquired, error) {
+	return nil, errPeerDown
+}
+
+func (c localClient) Handshake(ctx context.Context, v *proto.Version, _ ...grpc.CallOption) (*proto.Version, error) {
+	return c.n.getVersion(), nil
+}
+
+func (c localClient) HandleTransaction(ctx context.Context, tx *proto.Transaction, _ ...grpc.CallOption) (*proto.Acquired, error) {
+	return c.n.HandleTransaction(ctx, tx)
+}
+
+func (c localClient) HandleBlock(ctx context.Context, b *proto.Block, _ ...grpc.CallOption) (*proto.Acquired, error) {
+	return c.n.HandleBlock(ctx, b)
+}
+
+func (c localClient) GetHeaders(ctx context.Context, req *proto.RangeRequest, _ ...grpc.CallOption) (*proto.Headers, error) {
+	return c.n.GetHeaders(ctx, req)
+}
+
+func (c localClient) GetBlocks(ctx context.Context, req *proto.RangeRequest, _ ...grpc.CallOption) (*proto.Blocks, error) {
+	return c.n.GetBlocks(ctx, req)
+}
+
+func (c localClient) GetMerkleProof(ctx context.Context, req *proto.ProofRequest, _ ...grpc.CallOption) (*proto.MerkleProof, error) {
+	return c.n.GetMerkleProof(ctx, req)
+}
+
+// produceBlocks lets the validator node n add count blocks to its chain.
+func produceBlocks(t *testing.T, n *Node, count int) []*proto.Block {
+	blocks := make([]*proto.Block, count)
+	for i := 0; i < count; i++ {
+		block, _, err := n.createBlock()
+		require.Nil(t, err)
+		require.Nil(t, n.chain.AddBlock(block))
+		blocks[i] = block
+	}
+	return blocks
+}
+
+func TestValidatorsTakeTurns(t *testing.T) {
+	g, keys := validatorGenesis(3)
+	nodes := make([]*Node, len(keys))
+	for i, key := range keys {
+		nodes[i] = newTestNode(t, ServerConfig{ListenAddr: fmt.Sprintf(":%d", 3000+i), PrivateKy: key, Genesis: g})
+	}
+	for _, n := range nodes {
+		for _, peer := range nodes {
+			if peer != n {
+				n.peers[localClient{peer}] = peer.getVersion()
+			}
+		}
+	}
+
+	for height := 1; height <= 7; height++ {
+		// Every validator tries, only the one whose turn it is proposes.
+		blocks := make([]*proto.Block, len(nodes))
+		for i, n := range nodes {
+			block, err := n.proposeBlock()
+			require.Nil(t, err)
+			blocks[i] = block
+		}
+		proposer := height % len(nodes)
+		for i, block := range blocks {
+			if i != proposer {
+				require.Nil(t, block)
+			}
+		}
+		require.NotNil(t, blocks[proposer])
+		require.Equal(t, keys[proposer].Public().Bytes(), blocks[proposer].PublicKey)
+		require.Nil(t, nodes[proposer].broadcast(blocks[proposer]))
+
+		for _, n := range nodes {
+			require.Equal(t, height, n.chain.Height())
+			require.Equal(t, nodes[0].chain.Tip().Hash, n.chain.Tip().Hash)
+		}
+	}
+}
+
+func TestHandleOrphanBlockRequestsAncestors(t *testing.T) {
+	var (
+		validator = newTestNode(t, ServerConfig{ListenAddr: ":3000", PrivateKy: validatorKey()})
+		n         = newTestNode(t, ServerConfig{ListenAddr: ":4000"})
+	)
+	n.peers[localClient{validator}] = validator.getVersion()
+	blocks := produceBlocks(t, validator, 5)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(listenAddrKey, ":3000"))
+	_, err := n.HandleBlock(ctx, blocks[4])
+	require.Nil(t, err)
+
+	require.Eventually(t, func() bool {
+		return n.chain.Height() == 5
+	}, time.Second, time.Millisecond*10)
+	require.Equal(t, 0, n.chain.OrphanCount())
+}
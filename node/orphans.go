@@ -0,0 +1,238 @@
+package node
+
+import (
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/DenisBytes/GoChain/proto"
+)
+
+const (
+	maxOrphanBlocks = 100
+	orphanBlockTTL  = time.Minute * 10
+
+	maxOrphanTxs = 100
+	orphanTxTTL  = time.Minute * 20
+)
+
+type orphanBlock struct {
+	hash     string
+	block    *proto.Block
+	received time.Time
+}
+
+// orphanBlockPool holds blocks whose parent is not known yet, keyed by
+// the hash of the missing parent. The pool is bounded in size and in the
+// time an orphan may wait for its parent, so a peer cannot fill it up.
+type orphanBlockPool struct {
+	orphans  map[string]*orphanBlock
+	byParent map[string][]*orphanBlock
+	maxSize  int
+	ttl      time.Duration
+}
+
+func newOrphanBlockPool(maxSize int, ttl time.Duration) *orphanBlockPool {
+	return &orphanBlockPool{
+		orphans:  make(map[string]*orphanBlock),
+		byParent: make(map[string][]*orphanBlock),
+		maxSize:  maxSize,
+		ttl:      ttl,
+	}
+}
+
+func (pool *orphanBlockPool) Len() int {
+	return len(pool.orphans)
+}
+
+func (pool *orphanBlockPool) Has(hash string) bool {
+	_, ok := pool.orphans[hash]
+	return ok
+}
+
+func (pool *orphanBlockPool) Add(hash string, b *proto.Block) {
+	if pool.Has(hash) {
+		return
+	}
+	pool.expire()
+	for len(pool.orphans) >= pool.maxSize {
+		pool.remove(pool.oldest())
+	}
+
+	orphan := &orphanBlock{
+		hash:     hash,
+		block:    b,
+		received: time.Now(),
+	}
+	parent := hex.EncodeToString(b.Header.PrevHash)
+	pool.orphans[hash] = orphan
+	pool.byParent[parent] = append(pool.byParent[parent], orphan)
+}
+
+// TakeChildren removes and returns the orphans waiting for parent.
+func (pool *orphanBlockPool) TakeChildren(parent string) []*proto.Block {
+	children := pool.byParent[parent]
+	blocks := make([]*proto.Block, len(children))
+	for i, orphan := range children {
+		blocks[i] = orphan.block
+		pool.remove(orphan)
+	}
+	return blocks
+}
+
+func (pool *orphanBlockPool) expire() {
+	now := time.Now()
+	for _, orphan := range pool.orphans {
+		if now.Sub(orphan.received) > pool.ttl {
+			pool.remove(orphan)
+		}
+	}
+}
+
+func (pool *orphanBlockPool) oldest() *orphanBlock {
+	var oldest *orphanBlock
+	for _, orphan := range pool.orphans {
+		if oldest == nil || orphan.received.Before(oldest.received) {
+			oldest = orphan
+		}
+	}
+	return oldest
+}
+
+func (pool *orphanBlockPool) remove(orphan *orphanBlock) {
+	delete(pool.orphans, orphan.hash)
+
+	parent := hex.EncodeToString(orphan.block.Header.PrevHash)
+	siblings := pool.byParent[parent]
+	for i, sibling := range siblings {
+		if sibling == orphan {
+			siblings = append(siblings[:i], siblings[i+1:]...)
+			break
+		}
+	}
+	if len(siblings) == 0 {
+		delete(pool.byParent, parent)
+	} else {
+		pool.byParent[parent] = siblings
+	}
+}
+
+type orphanTx struct {
+	hash     string
+	tx       *proto.Transaction
+	missing  []string
+	received time.Time
+}
+
+// orphanTxPool holds transactions spending outputs of transactions that
+// are neither in the chain nor in the mempool yet, keyed by the hashes of
+// the missing parents. Like orphanBlockPool it is bounded in size and in
+// time.
+type orphanTxPool struct {
+	orphans  map[string]*orphanTx
+	byParent map[string][]*orphanTx
+	maxSize  int
+	ttl      time.Duration
+	lock     sync.Mutex
+}
+
+func newOrphanTxPool(maxSize int, ttl time.Duration) *orphanTxPool {
+	return &orphanTxPool{
+		orphans:  make(map[string]*orphanTx),
+		byParent: make(map[string][]*orphanTx),
+		maxSize:  maxSize,
+		ttl:      ttl,
+	}
+}
+
+func (pool *orphanTxPool) Len() int {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	return len(pool.orphans)
+}
+
+func (pool *orphanTxPool) Has(hash string) bool {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	_, ok := pool.orphans[hash]
+	return ok
+}
+
+// Add adds tx waiting for the transactions with the missing hashes.
+func (pool *orphanTxPool) Add(hash string, tx *proto.Transaction, missing []string) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	if _, ok := pool.orphans[hash]; ok {
+		return
+	}
+	pool.expire()
+	for len(pool.orphans) >= pool.maxSize {
+		pool.remove(pool.oldest())
+	}
+
+	orphan := &orphanTx{
+		hash:     hash,
+		tx:       tx,
+		missing:  missing,
+		received: time.Now(),
+	}
+	pool.orphans[hash] = orphan
+	for _, parent := range missing {
+		pool.byParent[parent] = append(pool.byParent[parent], orphan)
+	}
+}
+
+// TakeChildren removes and returns the orphans waiting for parent.
+func (pool *orphanTxPool) TakeChildren(parent string) []*proto.Transaction {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	children := pool.byParent[parent]
+	txx := make([]*proto.Transaction, len(children))
+	for i, orphan := range children {
+		txx[i] = orphan.tx
+		pool.remove(orphan)
+	}
+	return txx
+}
+
+func (pool *orphanTxPool) expire() {
+	now := time.Now()
+	for _, orphan := range pool.orphans {
+		if now.Sub(orphan.received) > pool.ttl {
+			pool.remove(orphan)
+		}
+	}
+}
+
+func (pool *orphanTxPool) oldest() *orphanTx {
+	var oldest *orphanTx
+	for _, orphan := range pool.orphans {
+		if oldest == nil || orphan.received.Before(oldest.received) {
+			oldest = orphan
+		}
+	}
+	return oldest
+}
+
+func (pool *orphanTxPool) remove(orphan *orphanTx) {
+	delete(pool.orphans, orphan.hash)
+
+	for _, parent := range orphan.missing {
+		siblings := pool.byParent[parent]
+		for i, sibling := range siblings {
+			if sibling == orphan {
+				siblings = append(siblings[:i], siblings[i+1:]...)
+				break
+			}
+		}
+		if len(siblings) == 0 {
+			delete(pool.byParent, parent)
+		} else {
+			pool.byParent[parent] = siblings
+		}
+	}
+}
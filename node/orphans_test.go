@@ -0,0 +1,109 @@
+package node
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/DenisBytes/GoChain/proto"
+	"github.com/DenisBytes/GoChain/types"
+)
+
+func TestOrphanBlocksConnectWhenParentArrives(t *testing.T) {
+	chain := NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
+	genesis, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+
+	b1 := blockOn(genesis)
+	b2 := blockOn(b1)
+	b3 := blockOn(b2)
+	side := blockOn(b1)
+
+	require.True(t, errors.Is(chain.AddBlock(b3), ErrOrphanBlock))
+	require.True(t, errors.Is(chain.AddBlock(b2), ErrOrphanBlock))
+	require.True(t, errors.Is(chain.AddBlock(side), ErrOrphanBlock))
+	require.Equal(t, 3, chain.OrphanCount())
+	require.Equal(t, 0, chain.Height())
+
+	require.Nil(t, chain.AddBlock(b1))
+	require.Equal(t, 0, chain.OrphanCount())
+	require.Equal(t, 3, chain.Height())
+	requireTip(t, chain, b3)
+	require.True(t, chain.HasBlock(types.HashBlock(side)))
+}
+
+func TestOrphanBlockWithInvalidSignature(t *testing.T) {
+	chain := NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
+	genesis, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+
+	b2 := blockOn(blockOn(genesis))
+	b2.Signature = b2.Signature[1:]
+	err = chain.AddBlock(b2)
+	require.NotNil(t, err)
+	require.False(t, errors.Is(err, ErrOrphanBlock))
+	require.Equal(t, 0, chain.OrphanCount())
+}
+
+func TestOrphanBlockPoolEviction(t *testing.T) {
+	chain := NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
+	genesis, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+
+	pool := newOrphanBlockPool(2, time.Hour)
+	orphans := []string{}
+	for i := 0; i < 3; i++ {
+		b := blockOn(blockOn(genesis))
+		hash := hex.EncodeToString(types.HashBlock(b))
+		pool.Add(hash, b)
+		orphans = append(orphans, hash)
+	}
+	require.Equal(t, 2, pool.Len())
+	require.False(t, pool.Has(orphans[0]))
+	require.True(t, pool.Has(orphans[1]))
+	require.True(t, pool.Has(orphans[2]))
+
+	pool = newOrphanBlockPool(10, time.Millisecond)
+	b := blockOn(blockOn(genesis))
+	pool.Add("expired", b)
+	time.Sleep(time.Millisecond * 5)
+	pool.Add("fresh", blockOn(blockOn(genesis)))
+	require.Equal(t, 1, pool.Len())
+	require.True(t, pool.Has("fresh"))
+	require.Empty(t, pool.TakeChildren(hex.EncodeToString(b.Header.PrevHash)))
+}
+
+func TestOrphanTxPoolEviction(t *testing.T) {
+	pool := newOrphanTxPool(2, time.Hour)
+	orphans := []string{}
+	for i := 0; i < 3; i++ {
+		tx := mempoolTx(nil)
+		hash := hex.EncodeToString(types.HashTransaction(tx))
+		pool.Add(hash, tx, []string{hex.EncodeToString(tx.Inputs[0].PrevTxHash)})
+		orphans = append(orphans, hash)
+	}
+	require.Equal(t, 2, pool.Len())
+	require.False(t, pool.Has(orphans[0]))
+	require.True(t, pool.Has(orphans[1]))
+	require.True(t, pool.Has(orphans[2]))
+
+	// An orphan missing two parents is promoted by either of them.
+	tx := mempoolTx(nil)
+	missing := []string{"parent1", "parent2"}
+	pool = newOrphanTxPool(10, time.Hour)
+	pool.Add("orphan", tx, missing)
+	require.Equal(t, []*proto.Transaction{tx}, pool.TakeChildren("parent2"))
+	require.Empty(t, pool.TakeChildren("parent1"))
+	require.Equal(t, 0, pool.Len())
+
+	pool = newOrphanTxPool(10, time.Millisecond)
+	pool.Add("expired", tx, missing)
+	time.Sleep(time.Millisecond * 5)
+	pool.Add("fresh", mempoolTx(nil), []string{"parent3"})
+	require.Equal(t, 1, pool.Len())
+	require.True(t, pool.Has("fresh"))
+	require.Empty(t, pool.TakeChildren("parent1"))
+}
@@ -0,0 +1,43 @@
+package node
+
+// RewardSchedule is the monetary policy of a chain: the amount the
+// coinbase transaction of a block at a given height may mint.
+type RewardSchedule interface {
+	Reward(height int) int64
+}
+
+// FixedReward mints the same amount in every block.
+type FixedReward int64
+
+func (r FixedReward) Reward(height int) int64 {
+	return int64(r)
+}
+
+// HalvingReward starts at Initial and halves every Interval blocks until
+// it reaches zero. It never halves when Interval is not positive.
+type HalvingReward struct {
+	Initial  int64
+	Interval int
+}
+
+func (r HalvingReward) Reward(height int) int64 {
+	if r.Interval <= 0 {
+		return r.Initial
+	}
+	halvings := height / r.Interval
+	if halvings >= 63 {
+		return 0
+	}
+	return r.Initial >> halvings
+}
+
+// TailEmission follows Schedule but never mints less than Tail, so
+// block producers keep being paid once the schedule ran out.
+type TailEmission struct {
+	Schedule RewardSchedule
+	Tail     int64
+}
+
+func (r TailEmission) Reward(height int) int64 {
+	return max(r.Schedule.Reward(height), r.Tail)
+}
@@ -0,0 +1,34 @@
+package node
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRewardSchedules(t *testing.T) {
+	halving := HalvingReward{Initial: 100, Interval: 10}
+	tests := []struct {
+		name     string
+		schedule RewardSchedule
+		height   int
+		reward   int64
+	}{
+		{"fixed", FixedReward(5), 1, 5},
+		{"fixed far", FixedReward(5), 1_000_000, 5},
+		{"halving start", halving, 1, 100},
+		{"halving end of first era", halving, 9, 100},
+		{"halving second era", halving, 10, 50},
+		{"halving third era", halving, 25, 25},
+		{"halving exhausted", halving, 10_000, 0},
+		{"halving without interval", HalvingReward{Initial: 100}, 1_000, 100},
+		{"halving with negative interval", HalvingReward{Initial: 100, Interval: -5}, 1_000, 100},
+		{"tail above", TailEmission{Schedule: halving, Tail: 10}, 15, 50},
+		{"tail below", TailEmission{Schedule: halving, Tail: 10}, 45, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.reward, tt.schedule.Reward(tt.height))
+		})
+	}
+}
@@ -0,0 +1,331 @@
+package node
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/DenisBytes/GoChain/errs"
+)
+
+const (
+	segmentPattern  = "segment-%06d.log"
+	maxSegmentSize  = 64 << 20
+	recordHeaderLen = 8
+
+	opPut    byte = 1
+	opDelete byte = 2
+)
+
+// DB is a key value store kept in append-only segment files inside a
+// directory. Every write is a record holding one or more operations:
+//
+//	length  uint32  length of the payload
+//	crc     uint32  CRC-32 (IEEE) of the payload
+//	payload         operations, each one being
+//	                kind (1 byte) | uvarint key length | key |
+//	                uvarint value length | value
+//
+// A record is synced to disk before the write returns, and a record
+// that was only partly written when the process died is discarded on
+// the next open, so a write is either fully applied or not at all.
+// The location of the latest value of every key is kept in memory.
+type DB struct {
+	lock       sync.RWMutex
+	dir        string
+	segments   []*os.File
+	activeSize int64
+	index      map[string]location
+	// segmentSize is the size after which a new segment is started.
+	segmentSize int64
+	// failed is set when a failed write could not be rolled back, the
+	// DB then refuses any further write.
+	failed error
+}
+
+type location struct {
+	segment int
+	offset  int64
+	length  int
+}
+
+type dbOp struct {
+	kind  byte
+	key   string
+	value []byte
+}
+
+// OpenDB opens the store in dir, creating the directory if needed, and
+// replays its segments to rebuild the index.
+func OpenDB(dir string) (*DB, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	db := &DB{
+		dir:         dir,
+		index:       make(map[string]location),
+		segmentSize: maxSegmentSize,
+	}
+
+	names, err := filepath.Glob(filepath.Join(dir, "segment-*.log"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		f, err := os.OpenFile(name, os.O_RDWR, 0o644)
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+		db.segments = append(db.segments, f)
+		last := i == len(names)-1
+		if err := db.replay(i, f, last); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("segment %s: %w", name, err)
+		}
+	}
+	if len(db.segments) == 0 {
+		if err := db.rotate(); err != nil {
+			return nil, err
+		}
+	}
+	return db, nil
+}
+
+// replay adds the records of a segment to the index. A torn record at
+// the end of the last segment is cut off, anywhere else it means the
+// segment is corrupted.
+func (db *DB) replay(segment int, f *os.File, last bool) error {
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return err
+	}
+
+	var offset int64
+	for offset < int64(len(data)) {
+		ops, err := decodeRecord(data[offset:])
+		if err != nil {
+			if !last {
+				return err
+			}
+			if err := f.Truncate(offset); err != nil {
+				return err
+			}
+			if err := f.Sync(); err != nil {
+				return err
+			}
+			break
+		}
+		size := db.apply(segment, offset, ops)
+		offset += size
+	}
+	if last {
+		db.activeSize = offset
+		_, err := f.Seek(offset, io.SeekStart)
+		return err
+	}
+	return nil
+}
+
+// apply updates the index with the operations of the record written at
+// offset and returns the size of the record.
+func (db *DB) apply(segment int, offset int64, ops []dbOp) int64 {
+	pos := offset + recordHeaderLen
+	for _, op := range ops {
+		pos += 1 + int64(uvarintLen(len(op.key))+len(op.key))
+		pos += int64(uvarintLen(len(op.value)))
+		switch op.kind {
+		case opPut:
+			db.index[op.key] = location{
+				segment: segment,
+				offset:  pos,
+				length:  len(op.value),
+			}
+		case opDelete:
+			delete(db.index, op.key)
+		}
+		pos += int64(len(op.value))
+	}
+	return pos - offset
+}
+
+func (db *DB) Get(key string) ([]byte, error) {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	if db.segments == nil {
+		return nil, ErrDBClosed
+	}
+	loc, ok := db.index[key]
+	if !ok {
+		return nil, errs.ErrNotFound
+	}
+	value := make([]byte, loc.length)
+	if _, err := db.segments[loc.segment].ReadAt(value, loc.offset); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func (db *DB) Put(key string, value []byte) error {
+	return db.write([]dbOp{{kind: opPut, key: key, value: value}})
+}
+
+func (db *DB) Delete(key string) error {
+	return db.write([]dbOp{{kind: opDelete, key: key}})
+}
+
+// write appends the operations as a single record and syncs it.
+func (db *DB) write(ops []dbOp) error {
+	record := encodeRecord(ops)
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if db.segments == nil {
+		return ErrDBClosed
+	}
+	if db.failed != nil {
+		return db.failed
+	}
+	if db.activeSize > 0 && db.activeSize+int64(len(record)) > db.segmentSize {
+		if err := db.rotate(); err != nil {
+			return err
+		}
+	}
+	active := db.segments[len(db.segments)-1]
+	if _, err := active.Write(record); err != nil {
+		return db.rollback(active, err)
+	}
+	if err := active.Sync(); err != nil {
+		return db.rollback(active, err)
+	}
+	db.apply(len(db.segments)-1, db.activeSize, ops)
+	db.activeSize += int64(len(record))
+	return nil
+}
+
+// rollback cuts off what a failed write left of its record, so the
+// active segment ends at activeSize again, and returns err. When the
+// segment cannot be cut, the DB is marked failed.
+func (db *DB) rollback(active *os.File, err error) error {
+	if terr := active.Truncate(db.activeSize); terr != nil {
+		db.failed = fmt.Errorf("database failed, rolling back a write: %w", terr)
+		return err
+	}
+	if _, serr := active.Seek(db.activeSize, io.SeekStart); serr != nil {
+		db.failed = fmt.Errorf("database failed, rolling back a write: %w", serr)
+	}
+	return err
+}
+
+// rotate starts a new segment file.
+func (db *DB) rotate() error {
+	name := filepath.Join(db.dir, fmt.Sprintf(segmentPattern, len(db.segments)))
+	f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := syncDir(db.dir); err != nil {
+		f.Close()
+		return err
+	}
+	db.segments = append(db.segments, f)
+	db.activeSize = 0
+	return nil
+}
+
+func (db *DB) Close() error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	var err error
+	for _, f := range db.segments {
+		if cerr := f.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	db.segments = nil
+	return err
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+func encodeRecord(ops []dbOp) []byte {
+	payload := []byte{}
+	for _, op := range ops {
+		payload = append(payload, op.kind)
+		payload = binary.AppendUvarint(payload, uint64(len(op.key)))
+		payload = append(payload, op.key...)
+		payload = binary.AppendUvarint(payload, uint64(len(op.value)))
+		payload = append(payload, op.value...)
+	}
+
+	record := make([]byte, recordHeaderLen, recordHeaderLen+len(payload))
+	binary.BigEndian.PutUint32(record[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:8], crc32.ChecksumIEEE(payload))
+	return append(record, payload...)
+}
+
+func decodeRecord(data []byte) ([]dbOp, error) {
+	if len(data) < recordHeaderLen {
+		return nil, fmt.Errorf("short record header")
+	}
+	length := int(binary.BigEndian.Uint32(data[0:4]))
+	if len(data)-recordHeaderLen < length {
+		return nil, fmt.Errorf("short record payload")
+	}
+	payload := data[recordHeaderLen : recordHeaderLen+length]
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(data[4:8]) {
+		return nil, fmt.Errorf("record checksum mismatch")
+	}
+
+	ops := []dbOp{}
+	for len(payload) > 0 {
+		op := dbOp{kind: payload[0]}
+		if op.kind != opPut && op.kind != opDelete {
+			return nil, fmt.Errorf("unknown operation %d", op.kind)
+		}
+		payload = payload[1:]
+
+		key, rest, err := readBytes(payload)
+		if err != nil {
+			return nil, err
+		}
+		value, rest, err := readBytes(rest)
+		if err != nil {
+			return nil, err
+		}
+		op.key = string(key)
+		op.value = value
+		payload = rest
+		ops = append(ops, op)
+	}
+	return ops, nil
+}
+
+func readBytes(data []byte) ([]byte, []byte, error) {
+	length, n := binary.Uvarint(data)
+	if n <= 0 || uint64(len(data)-n) < length {
+		return nil, nil, fmt.Errorf("malformed operation")
+	}
+	end := n + int(length)
+	return data[n:end], data[end:], nil
+}
+
+func uvarintLen(x int) int {
+	return len(binary.AppendUvarint(nil, uint64(x)))
+}
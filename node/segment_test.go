@@ -0,0 +1,136 @@
+package node
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/DenisBytes/GoChain/errs"
+)
+
+func TestDBReopen(t *testing.T) {
+	dir := t.TempDir()
+	db, err := OpenDB(dir)
+	require.Nil(t, err)
+	require.Nil(t, db.Put("foo", []byte("bar")))
+	require.Nil(t, db.Put("baz", []byte("qux")))
+	require.Nil(t, db.Put("foo", []byte("bar2")))
+	require.Nil(t, db.Delete("baz"))
+	require.Nil(t, db.Close())
+
+	db, err = OpenDB(dir)
+	require.Nil(t, err)
+	defer db.Close()
+	value, err := db.Get("foo")
+	require.Nil(t, err)
+	require.Equal(t, []byte("bar2"), value)
+	_, err = db.Get("baz")
+	require.ErrorIs(t, err, errs.ErrNotFound)
+}
+
+func TestDBClosed(t *testing.T) {
+	db, err := OpenDB(t.TempDir())
+	require.Nil(t, err)
+	require.Nil(t, db.Put("foo", []byte("bar")))
+	require.Nil(t, db.Close())
+
+	_, err = db.Get("foo")
+	require.ErrorIs(t, err, ErrDBClosed)
+	require.ErrorIs(t, db.Put("foo", []byte("baz")), ErrDBClosed)
+	require.ErrorIs(t, db.Delete("foo"), ErrDBClosed)
+}
+
+func TestDBDiscardsTornWrite(t *testing.T) {
+	dir := t.TempDir()
+	db, err := OpenDB(dir)
+	require.Nil(t, err)
+	require.Nil(t, db.Put("foo", []byte("bar")))
+	require.Nil(t, db.Put("baz", []byte("qux")))
+	require.Nil(t, db.Close())
+
+	// Cut the last record in half as if the process died while writing it.
+	name := filepath.Join(dir, "segment-000000.log")
+	info, err := os.Stat(name)
+	require.Nil(t, err)
+	require.Nil(t, os.Truncate(name, info.Size()-3))
+
+	db, err = OpenDB(dir)
+	require.Nil(t, err)
+	value, err := db.Get("foo")
+	require.Nil(t, err)
+	require.Equal(t, []byte("bar"), value)
+	_, err = db.Get("baz")
+	require.ErrorIs(t, err, errs.ErrNotFound)
+
+	// New writes go after the last complete record.
+	require.Nil(t, db.Put("baz", []byte("quux")))
+	require.Nil(t, db.Close())
+
+	db, err = OpenDB(dir)
+	require.Nil(t, err)
+	defer db.Close()
+	value, err = db.Get("baz")
+	require.Nil(t, err)
+	require.Equal(t, []byte("quux"), value)
+}
+
+func TestDBRollsBackFailedWrite(t *testing.T) {
+	dir := t.TempDir()
+	db, err := OpenDB(dir)
+	require.Nil(t, err)
+	require.Nil(t, db.Put("foo", []byte("bar")))
+
+	// A write that failed halfway through its record.
+	active := db.segments[0]
+	_, err = active.Write(encodeRecord([]dbOp{{kind: opPut, key: "baz", value: []byte("qux")}})[:5])
+	require.Nil(t, err)
+	writeErr := errors.New("disk full")
+	require.ErrorIs(t, db.rollback(active, writeErr), writeErr)
+
+	require.Nil(t, db.Put("baz", []byte("quux")))
+	require.Nil(t, db.Close())
+	db, err = OpenDB(dir)
+	require.Nil(t, err)
+	value, err := db.Get("baz")
+	require.Nil(t, err)
+	require.Equal(t, []byte("quux"), value)
+
+	// A segment that cannot be cut any more makes the DB refuse writes.
+	readOnly, err := os.Open(active.Name())
+	require.Nil(t, err)
+	db.segments[0].Close()
+	db.segments[0] = readOnly
+	require.NotNil(t, db.Put("foo", []byte("bar2")))
+	require.NotNil(t, db.failed)
+	require.ErrorIs(t, db.Put("foo", []byte("bar3")), db.failed)
+	require.Nil(t, db.Close())
+}
+
+func TestDBRotatesSegments(t *testing.T) {
+	dir := t.TempDir()
+	db, err := OpenDB(dir)
+	require.Nil(t, err)
+	db.segmentSize = 64
+
+	keys := []string{"a", "b", "c", "d", "e", "f"}
+	for _, key := range keys {
+		require.Nil(t, db.Put(key, []byte("some value for "+key)))
+	}
+	require.Nil(t, db.Close())
+
+	names, err := filepath.Glob(filepath.Join(dir, "segment-*.log"))
+	require.Nil(t, err)
+	require.Greater(t, len(names), 1)
+
+	db, err = OpenDB(dir)
+	require.Nil(t, err)
+	defer db.Close()
+	for _, key := range keys {
+		value, err := db.Get(key)
+		require.Nil(t, err)
+		require.Equal(t, []byte("some value for "+key), value)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"sync"
 
+	"github.com/DenisBytes/GoChain/errs"
 	"github.com/DenisBytes/GoChain/proto"
 	"github.com/DenisBytes/GoChain/types"
 )
@@ -12,6 +13,7 @@ import (
 type UTXOStorer interface {
 	Put(*UTXO) error
 	Get(string) (*UTXO, error)
+	Delete(string) error
 }
 
 type MemoryUTXOStore struct {
@@ -31,7 +33,7 @@ func (s *MemoryUTXOStore) Get(hash string) (*UTXO, error) {
 
 	utxo, ok := s.data[hash]
 	if !ok {
-		return nil, fmt.Errorf("could not find utxo with hash %s", hash)
+		return nil, fmt.Errorf("%w: utxo %s", errs.ErrNotFound, hash)
 	}
 
 	return utxo, nil
@@ -48,6 +50,15 @@ func (s *MemoryUTXOStore) Put(utxo *UTXO) error {
 	return nil
 }
 
+func (s *MemoryUTXOStore) Delete(hash string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.data, hash)
+
+	return nil
+}
+
 type TXStorer interface {
 	Put(*proto.Transaction) error
 	Get(string) (*proto.Transaction, error)
@@ -69,7 +80,7 @@ func (s *MemoryTXStore) Get(hash string) (*proto.Transaction, error) {
 	defer s.lock.RUnlock()
 	tx, ok := s.txx[hash]
 	if !ok {
-		return nil, fmt.Errorf("could not find tx with hash %s", hash)
+		return nil, fmt.Errorf("%w: tx %s", errs.ErrNotFound, hash)
 	}
 	return tx, nil
 }
@@ -103,7 +114,7 @@ func (s *MemoryBlockStore) Get(hash string) (*proto.Block, error) {
 	defer s.lock.RUnlock()
 	block, ok := s.blocks[hash]
 	if !ok {
-		return nil, fmt.Errorf("block with hash [%s] does not exist", hash)
+		return nil, fmt.Errorf("%w: block [%s]", errs.ErrNotFound, hash)
 	}
 	return block, nil
 }
@@ -0,0 +1,135 @@
+package node
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	pb "google.golang.org/protobuf/proto"
+
+	"github.com/DenisBytes/GoChain/crypto"
+	"github.com/DenisBytes/GoChain/proto"
+	"github.com/DenisBytes/GoChain/types"
+	"github.com/DenisBytes/GoChain/util"
+)
+
+type storers struct {
+	blocks BlockStorer
+	txx    TXStorer
+	utxos  UTXOStorer
+}
+
+// allStorers returns every storer implementation, each of them has to
+// pass the same conformance tests.
+func allStorers(t *testing.T) map[string]storers {
+	db, err := OpenDB(t.TempDir())
+	require.Nil(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	return map[string]storers{
+		"memory": {
+			blocks: NewMemoryBlockStore(),
+			txx:    NewMemoryTXStore(),
+			utxos:  NewMemoryUTXOStore(),
+		},
+		"disk": {
+			blocks: NewDiskBlockStore(db),
+			txx:    NewDiskTXStore(db),
+			utxos:  NewDiskUTXOStore(db),
+		},
+	}
+}
+
+func randomTx() *proto.Transaction {
+	return &proto.Transaction{
+		Version: 1,
+		Inputs: []*proto.TxInput{
+			{
+				PrevTxHash:   util.RandomHash(),
+				PrevOutIndex: 1,
+				PublicKey:    util.RandomHash(),
+			},
+		},
+		Outputs: []*proto.TxOutput{
+			{
+				Amount:  42,
+				Address: util.RandomHash()[:20],
+			},
+		},
+	}
+}
+
+func TestBlockStorer(t *testing.T) {
+	for name, s := range allStorers(t) {
+		t.Run(name, func(t *testing.T) {
+			block := util.RandomBlock()
+			block.Transactions = []*proto.Transaction{randomTx()}
+			hash := hex.EncodeToString(types.HashBlock(block))
+
+			_, err := s.blocks.Get(hash)
+			require.NotNil(t, err)
+
+			require.Nil(t, s.blocks.Put(block))
+			fetched, err := s.blocks.Get(hash)
+			require.Nil(t, err)
+			require.True(t, pb.Equal(block, fetched))
+
+			// Putting the same block again is harmless.
+			require.Nil(t, s.blocks.Put(block))
+			fetched, err = s.blocks.Get(hash)
+			require.Nil(t, err)
+			require.True(t, pb.Equal(block, fetched))
+		})
+	}
+}
+
+func TestTXStorer(t *testing.T) {
+	for name, s := range allStorers(t) {
+		t.Run(name, func(t *testing.T) {
+			tx := randomTx()
+			hash := hex.EncodeToString(types.HashTransaction(tx))
+
+			_, err := s.txx.Get(hash)
+			require.NotNil(t, err)
+
+			require.Nil(t, s.txx.Put(tx))
+			fetched, err := s.txx.Get(hash)
+			require.Nil(t, err)
+			require.True(t, pb.Equal(tx, fetched))
+		})
+	}
+}
+
+func TestUTXOStorer(t *testing.T) {
+	for name, s := range allStorers(t) {
+		t.Run(name, func(t *testing.T) {
+			utxo := &UTXO{
+				Hash:     hex.EncodeToString(util.RandomHash()),
+				OutIndex: 3,
+				Amount:   100,
+				Address:  crypto.GeneratePrivateKey().Public().Address().Bytes(),
+			}
+			key := fmt.Sprintf("%s_%d", utxo.Hash, utxo.OutIndex)
+
+			_, err := s.utxos.Get(key)
+			require.NotNil(t, err)
+
+			require.Nil(t, s.utxos.Put(utxo))
+			fetched, err := s.utxos.Get(key)
+			require.Nil(t, err)
+			require.Equal(t, *utxo, *fetched)
+
+			fetched.Spent = true
+			require.Nil(t, s.utxos.Put(fetched))
+			fetched, err = s.utxos.Get(key)
+			require.Nil(t, err)
+			require.True(t, fetched.Spent)
+
+			require.Nil(t, s.utxos.Delete(key))
+			_, err = s.utxos.Get(key)
+			require.NotNil(t, err)
+			require.Nil(t, s.utxos.Delete(key))
+		})
+	}
+}
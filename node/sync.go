@@ -0,0 +1,312 @@
+package node
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/DenisBytes/GoChain/errs"
+	"github.com/DenisBytes/GoChain/proto"
+	"github.com/DenisBytes/GoChain/types"
+)
+
+const (
+	maxHeadersPerRequest  = 500
+	maxBlocksPerRequest   = 50
+	defaultRequestTimeout = 10 * time.Second
+)
+
+// SyncProgress reports how far a node is in downloading the chain of
+// its tallest peer.
+type SyncProgress struct {
+	Syncing bool
+	Height  int
+	Target  int
+}
+
+func (n *Node) SyncProgress() SyncProgress {
+	n.syncLock.RLock()
+	defer n.syncLock.RUnlock()
+
+	return SyncProgress{
+		Syncing: n.syncing,
+		Height:  n.chain.Height(),
+		Target:  n.syncTarget,
+	}
+}
+
+func (n *Node) GetHeaders(ctx context.Context, req *proto.RangeRequest) (*proto.Headers, error) {
+	to := req.To
+	if to-req.From >= maxHeadersPerRequest {
+		to = req.From + maxHeadersPerRequest - 1
+	}
+	headers, err := n.chain.GetHeaders(int(req.From), int(to))
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &proto.Headers{Headers: headers}, nil
+}
+
+func (n *Node) GetBlocks(ctx context.Context, req *proto.RangeRequest) (*proto.Blocks, error) {
+	to := req.To
+	if to-req.From >= maxBlocksPerRequest {
+		to = req.From + maxBlocksPerRequest - 1
+	}
+	headers, err := n.chain.GetHeaders(int(req.From), int(to))
+	if err != nil {
+		return nil, statusError(err)
+	}
+	blocks := make([]*proto.Block, len(headers))
+	for i, header := range headers {
+		block, err := n.chain.GetBlockByHash(types.HashHeader(header))
+		if err != nil {
+			return nil, statusError(err)
+		}
+		blocks[i] = block
+	}
+	return &proto.Blocks{Blocks: blocks}, nil
+}
+
+// syncChain downloads the chain of the tallest peer until the node has
+// caught up with it. Headers are fetched first from that peer, block
+// bodies are then fetched in parallel from every peer that claims to
+// have them and added to the chain in order. A peer that fails to send
+// the headers is passed over for the next tallest one.
+func (n *Node) syncChain() {
+	n.syncLock.Lock()
+	if n.syncing {
+		n.syncLock.Unlock()
+		return
+	}
+	n.syncing = true
+	n.syncLock.Unlock()
+
+	defer func() {
+		n.syncLock.Lock()
+		n.syncing = false
+		n.syncLock.Unlock()
+	}()
+
+	failed := make(map[proto.NodeClient]bool)
+	for !n.stopping() {
+		peer, target := n.tallestPeer(failed)
+		height := n.chain.Height()
+		if peer == nil || target <= height {
+			return
+		}
+
+		n.syncLock.Lock()
+		n.syncTarget = target
+		n.syncLock.Unlock()
+		n.logger.Infow("starting chain sync", "we", n.ListenAddr, "height", height, "target", target)
+
+		headers, err := n.downloadHeaders(peer, height, target)
+		if err != nil {
+			n.logger.Errorw("header download failed", "we", n.ListenAddr, "err", err)
+			failed[peer] = true
+			continue
+		}
+		if len(headers) == 0 {
+			return
+		}
+		if err := n.downloadBlocks(headers); err != nil {
+			n.logger.Errorw("block download failed", "we", n.ListenAddr, "err", err)
+			return
+		}
+		n.logger.Infow("chain sync done", "we", n.ListenAddr, "height", n.chain.Height())
+	}
+}
+
+// tallestPeer returns the tallest peer that is not in skip.
+func (n *Node) tallestPeer(skip map[proto.NodeClient]bool) (proto.NodeClient, int) {
+	n.peerLock.RLock()
+	defer n.peerLock.RUnlock()
+
+	var (
+		tallest proto.NodeClient
+		height  = -1
+	)
+	for c, v := range n.peers {
+		if int(v.Height) > height && !skip[c] {
+			tallest = c
+			height = int(v.Height)
+		}
+	}
+	return tallest, height
+}
+
+// downloadHeaders fetches the headers above our current height up to
+// target and checks that they link to our tip and to each other.
+func (n *Node) downloadHeaders(peer proto.NodeClient, height, target int) ([]*proto.Header, error) {
+	tip, err := n.chain.GetHeaders(height, height)
+	if err != nil {
+		return nil, err
+	}
+	prev := tip[0]
+
+	headers := []*proto.Header{}
+	for from := height + 1; from <= target; {
+		ctx, cancel := n.requestContext()
+		resp, err := peer.GetHeaders(ctx, &proto.RangeRequest{
+			From: int32(from),
+			To:   int32(target),
+		})
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+		if len(resp.Headers) == 0 {
+			break
+		}
+		for _, header := range resp.Headers {
+			if header.Height != prev.Height+1 {
+				return nil, fmt.Errorf("%w: header at height %d does not follow height %d", errs.ErrBadHeight, header.Height, prev.Height)
+			}
+			if !bytes.Equal(header.PrevHash, types.HashHeader(prev)) {
+				return nil, fmt.Errorf("%w: header at height %d does not link to its parent", errs.ErrBadPrevHash, header.Height)
+			}
+			headers = append(headers, header)
+			prev = header
+		}
+		from += len(resp.Headers)
+	}
+	return headers, nil
+}
+
+// downloadBlocks fetches the bodies of the given headers in batches,
+// spreading the batches over the peers that are tall enough to serve
+// them, and adds the blocks to the chain in order.
+func (n *Node) downloadBlocks(headers []*proto.Header) error {
+	type batch struct {
+		index  int
+		blocks []*proto.Block
+		err    error
+	}
+
+	var (
+		batches = (len(headers) + maxBlocksPerRequest - 1) / maxBlocksPerRequest
+		results = make(chan batch, batches)
+		wg      sync.WaitGroup
+	)
+	for i := 0; i < batches; i++ {
+		start := i * maxBlocksPerRequest
+		end := start + maxBlocksPerRequest
+		if end > len(headers) {
+			end = len(headers)
+		}
+
+		wg.Add(1)
+		go func(index int, headers []*proto.Header) {
+			defer wg.Done()
+			blocks, err := n.downloadBatch(index, headers)
+			results <- batch{index: index, blocks: blocks, err: err}
+		}(i, headers[start:end])
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var (
+		pending = make(map[int][]*proto.Block)
+		next    = 0
+	)
+	for result := range results {
+		if result.err != nil {
+			return result.err
+		}
+		pending[result.index] = result.blocks
+		for blocks, ok := pending[next]; ok; blocks, ok = pending[next] {
+			for _, block := range blocks {
+				// Blocks also come in through HandleBlock meanwhile, the
+				// ones it got first are progress as well.
+				err := n.chain.AddBlock(block)
+				if errors.Is(err, ErrOrphanBlock) {
+					continue
+				}
+				if err != nil && !errors.Is(err, errs.ErrDuplicateBlock) {
+					return err
+				}
+				n.knownBlocks.Add(hex.EncodeToString(types.HashBlock(block)))
+			}
+			delete(pending, next)
+			next++
+
+			n.logger.Infow("sync progress",
+				"we", n.ListenAddr,
+				"height", n.chain.Height(),
+				"target", headers[len(headers)-1].Height)
+		}
+	}
+	return nil
+}
+
+// downloadBatch asks the peers that are tall enough for the blocks of
+// the given headers, starting with a different peer for every batch,
+// until one of them returns the bodies matching the headers.
+func (n *Node) downloadBatch(index int, headers []*proto.Header) ([]*proto.Block, error) {
+	var (
+		from  = headers[0].Height
+		to    = headers[len(headers)-1].Height
+		peers = n.peersWithHeight(int(to))
+		err   = fmt.Errorf("no peer to download blocks [%d-%d] from", from, to)
+	)
+	for i := range peers {
+		peer := peers[(index+i)%len(peers)]
+		var resp *proto.Blocks
+		ctx, cancel := n.requestContext()
+		resp, err = peer.GetBlocks(ctx, &proto.RangeRequest{From: from, To: to})
+		cancel()
+		if err != nil {
+			continue
+		}
+		if err = matchHeaders(resp.Blocks, headers); err != nil {
+			continue
+		}
+		return resp.Blocks, nil
+	}
+	return nil, err
+}
+
+// requestContext is the context of a request to a peer. It is done after
+// RequestTimeout or as soon as the node stops.
+func (n *Node) requestContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(n.outgoingContext(), n.RequestTimeout)
+	go func() {
+		select {
+		case <-n.quit:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+func matchHeaders(blocks []*proto.Block, headers []*proto.Header) error {
+	if len(blocks) != len(headers) {
+		return fmt.Errorf("%w: expected %d blocks got %d", errs.ErrMalformed, len(headers), len(blocks))
+	}
+	for i, block := range blocks {
+		if block.Header == nil || !bytes.Equal(types.HashBlock(block), types.HashHeader(headers[i])) {
+			return fmt.Errorf("%w: block at height %d does not match its header", errs.ErrMalformed, headers[i].Height)
+		}
+	}
+	return nil
+}
+
+func (n *Node) peersWithHeight(height int) []proto.NodeClient {
+	n.peerLock.RLock()
+	defer n.peerLock.RUnlock()
+
+	peers := []proto.NodeClient{}
+	for c, v := range n.peers {
+		if int(v.Height) >= height {
+			peers = append(peers, c)
+		}
+	}
+	return peers
+}
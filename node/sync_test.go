@@ -0,0 +1,140 @@
+package node
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/DenisBytes/GoChain/proto"
+	"github.com/DenisBytes/GoChain/types"
+)
+
+func TestGetHeadersAndBlocks(t *testing.T) {
+	validator := newTestNode(t, ServerConfig{PrivateKy: validatorKey()})
+	produceBlocks(t, validator, maxBlocksPerRequest+10)
+
+	headers, err := validator.GetHeaders(context.Background(), &proto.RangeRequest{From: 1, To: 5})
+	require.Nil(t, err)
+	require.Len(t, headers.Headers, 5)
+	require.Equal(t, int32(1), headers.Headers[0].Height)
+
+	blocks, err := validator.GetBlocks(context.Background(), &proto.RangeRequest{From: 1, To: 1000})
+	require.Nil(t, err)
+	require.Len(t, blocks.Blocks, maxBlocksPerRequest)
+	for i, block := range blocks.Blocks {
+		expected, err := validator.chain.GetBlockByHeight(i + 1)
+		require.Nil(t, err)
+		require.Equal(t, expected, block)
+	}
+}
+
+func TestSyncChain(t *testing.T) {
+	var (
+		validator = newTestNode(t, ServerConfig{PrivateKy: validatorKey()})
+		relay     = newTestNode(t, ServerConfig{})
+		n         = newTestNode(t, ServerConfig{})
+	)
+	for _, block := range produceBlocks(t, validator, 3*maxBlocksPerRequest+7) {
+		require.Nil(t, relay.chain.AddBlock(block))
+	}
+
+	n.peers[localClient{validator}] = validator.getVersion()
+	n.peers[localClient{relay}] = relay.getVersion()
+	n.syncChain()
+
+	require.Equal(t, validator.chain.Height(), n.chain.Height())
+	tip, err := n.chain.GetBlockByHeight(n.chain.Height())
+	require.Nil(t, err)
+	expected, err := validator.chain.GetBlockByHeight(validator.chain.Height())
+	require.Nil(t, err)
+	require.Equal(t, types.HashBlock(expected), types.HashBlock(tip))
+
+	progress := n.SyncProgress()
+	require.False(t, progress.Syncing)
+	require.Equal(t, progress.Target, progress.Height)
+}
+
+func TestSyncChainPassesOverHungPeer(t *testing.T) {
+	var (
+		validator = newTestNode(t, ServerConfig{PrivateKy: validatorKey()})
+		n         = newTestNode(t, ServerConfig{RequestTimeout: 50 * time.Millisecond})
+	)
+	produceBlocks(t, validator, 2*maxBlocksPerRequest)
+
+	n.peers[hungClient{localClient{validator}}] = &proto.Version{Height: int32(validator.chain.Height() + 1)}
+	n.peers[localClient{validator}] = validator.getVersion()
+	n.syncChain()
+	require.Equal(t, validator.chain.Height(), n.chain.Height())
+}
+
+func TestDownloadBlocksSkipsBlocksAlreadyAdded(t *testing.T) {
+	var (
+		validator = newTestNode(t, ServerConfig{PrivateKy: validatorKey()})
+		n         = newTestNode(t, ServerConfig{})
+	)
+	blocks := produceBlocks(t, validator, 5)
+	n.peers[localClient{validator}] = validator.getVersion()
+	headers, err := n.downloadHeaders(localClient{validator}, 0, 5)
+	require.Nil(t, err)
+
+	// Blocks handed over by HandleBlock while the headers were fetched.
+	require.Nil(t, n.chain.AddBlock(blocks[0]))
+	require.Nil(t, n.chain.AddBlock(blocks[1]))
+	require.Nil(t, n.downloadBlocks(headers))
+	require.Equal(t, 5, n.chain.Height())
+}
+
+func TestBlockFromPeerAheadStartsSync(t *testing.T) {
+	var (
+		validator = newTestNode(t, ServerConfig{ListenAddr: ":3000", PrivateKy: validatorKey()})
+		n         = newTestNode(t, ServerConfig{ListenAddr: ":4000"})
+	)
+	// The handshake happened while both were at genesis.
+	n.peers[localClient{validator}] = validator.getVersion()
+	blocks := produceBlocks(t, validator, 2*maxBlocksPerRequest)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(listenAddrKey, ":3000"))
+	_, err := n.HandleBlock(ctx, blocks[len(blocks)-1])
+	require.Nil(t, err)
+	require.Eventually(t, func() bool {
+		return n.chain.Height() == validator.chain.Height()
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, validator.chain.Height(), int(n.peers[localClient{validator}].Height))
+}
+
+func TestSyncChainRejectsUnlinkedHeaders(t *testing.T) {
+	var (
+		validator = newTestNode(t, ServerConfig{PrivateKy: validatorKey()})
+		n         = newTestNode(t, ServerConfig{})
+	)
+	produceBlocks(t, validator, 5)
+
+	peer := localClient{validator}
+	_, err := n.downloadHeaders(peer, 0, 5)
+	require.Nil(t, err)
+
+	// Pretend we are on a different chain at height 1.
+	other := newTestNode(t, ServerConfig{PrivateKy: validatorKey()})
+	produceBlocks(t, other, 1)
+	_, err = other.downloadHeaders(peer, 1, 5)
+	require.NotNil(t, err)
+}
+
+// hungClient is a peer that never answers the sync requests.
+type hungClient struct {
+	localClient
+}
+
+func (hungClient) GetHeaders(ctx context.Context, _ *proto.RangeRequest, _ ...grpc.CallOption) (*proto.Headers, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (hungClient) GetBlocks(ctx context.Context, _ *proto.RangeRequest, _ ...grpc.CallOption) (*proto.Blocks, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
@@ -1,4 +1,8 @@
 // Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             v3.14.0
+// source: proto/types.proto
 
 package proto
 
@@ -20,6 +24,10 @@ const _ = grpc.SupportPackageIsVersion7
 type NodeClient interface {
 	Handshake(ctx context.Context, in *Version, opts ...grpc.CallOption) (*Version, error)
 	HandleTransaction(ctx context.Context, in *Transaction, opts ...grpc.CallOption) (*Acquired, error)
+	HandleBlock(ctx context.Context, in *Block, opts ...grpc.CallOption) (*Acquired, error)
+	GetHeaders(ctx context.Context, in *RangeRequest, opts ...grpc.CallOption) (*Headers, error)
+	GetBlocks(ctx context.Context, in *RangeRequest, opts ...grpc.CallOption) (*Blocks, error)
+	GetMerkleProof(ctx context.Context, in *ProofRequest, opts ...grpc.CallOption) (*MerkleProof, error)
 }
 
 type nodeClient struct {
@@ -48,12 +56,52 @@ func (c *nodeClient) HandleTransaction(ctx context.Context, in *Transaction, opt
 	return out, nil
 }
 
+func (c *nodeClient) HandleBlock(ctx context.Context, in *Block, opts ...grpc.CallOption) (*Acquired, error) {
+	out := new(Acquired)
+	err := c.cc.Invoke(ctx, "/Node/HandleBlock", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nodeClient) GetHeaders(ctx context.Context, in *RangeRequest, opts ...grpc.CallOption) (*Headers, error) {
+	out := new(Headers)
+	err := c.cc.Invoke(ctx, "/Node/GetHeaders", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nodeClient) GetBlocks(ctx context.Context, in *RangeRequest, opts ...grpc.CallOption) (*Blocks, error) {
+	out := new(Blocks)
+	err := c.cc.Invoke(ctx, "/Node/GetBlocks", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nodeClient) GetMerkleProof(ctx context.Context, in *ProofRequest, opts ...grpc.CallOption) (*MerkleProof, error) {
+	out := new(MerkleProof)
+	err := c.cc.Invoke(ctx, "/Node/GetMerkleProof", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // NodeServer is the server API for Node service.
 // All implementations must embed UnimplementedNodeServer
 // for forward compatibility
 type NodeServer interface {
 	Handshake(context.Context, *Version) (*Version, error)
 	HandleTransaction(context.Context, *Transaction) (*Acquired, error)
+	HandleBlock(context.Context, *Block) (*Acquired, error)
+	GetHeaders(context.Context, *RangeRequest) (*Headers, error)
+	GetBlocks(context.Context, *RangeRequest) (*Blocks, error)
+	GetMerkleProof(context.Context, *ProofRequest) (*MerkleProof, error)
 	mustEmbedUnimplementedNodeServer()
 }
 
@@ -67,6 +115,18 @@ func (UnimplementedNodeServer) Handshake(context.Context, *Version) (*Version, e
 func (UnimplementedNodeServer) HandleTransaction(context.Context, *Transaction) (*Acquired, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method HandleTransaction not implemented")
 }
+func (UnimplementedNodeServer) HandleBlock(context.Context, *Block) (*Acquired, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method HandleBlock not implemented")
+}
+func (UnimplementedNodeServer) GetHeaders(context.Context, *RangeRequest) (*Headers, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetHeaders not implemented")
+}
+func (UnimplementedNodeServer) GetBlocks(context.Context, *RangeRequest) (*Blocks, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBlocks not implemented")
+}
+func (UnimplementedNodeServer) GetMerkleProof(context.Context, *ProofRequest) (*MerkleProof, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMerkleProof not implemented")
+}
 func (UnimplementedNodeServer) mustEmbedUnimplementedNodeServer() {}
 
 // UnsafeNodeServer may be embedded to opt out of forward compatibility for this service.
@@ -116,6 +176,78 @@ func _Node_HandleTransaction_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Node_HandleBlock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Block)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NodeServer).HandleBlock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Node/HandleBlock",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NodeServer).HandleBlock(ctx, req.(*Block))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Node_GetHeaders_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RangeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NodeServer).GetHeaders(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Node/GetHeaders",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NodeServer).GetHeaders(ctx, req.(*RangeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Node_GetBlocks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RangeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NodeServer).GetBlocks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Node/GetBlocks",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NodeServer).GetBlocks(ctx, req.(*RangeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Node_GetMerkleProof_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ProofRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NodeServer).GetMerkleProof(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Node/GetMerkleProof",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NodeServer).GetMerkleProof(ctx, req.(*ProofRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Node_ServiceDesc is the grpc.ServiceDesc for Node service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -131,6 +263,22 @@ var Node_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "HandleTransaction",
 			Handler:    _Node_HandleTransaction_Handler,
 		},
+		{
+			MethodName: "HandleBlock",
+			Handler:    _Node_HandleBlock_Handler,
+		},
+		{
+			MethodName: "GetHeaders",
+			Handler:    _Node_GetHeaders_Handler,
+		},
+		{
+			MethodName: "GetBlocks",
+			Handler:    _Node_GetBlocks_Handler,
+		},
+		{
+			MethodName: "GetMerkleProof",
+			Handler:    _Node_GetMerkleProof_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/types.proto",
@@ -4,59 +4,39 @@ import (
 	"bytes"
 	"crypto/sha256"
 
-	pb "google.golang.org/protobuf/proto"
-
 	"github.com/DenisBytes/GoChain/crypto"
 	"github.com/DenisBytes/GoChain/proto"
-	"github.com/cbergoon/merkletree"
 )
 
-type TxHash struct {
-	hash []byte
-}
-
-func NewTxHash(hash []byte) TxHash {
-	return TxHash{
-		hash: hash,
-	}
-}
-
-func (h TxHash) CalculateHash() ([]byte, error) {
-	return h.hash, nil
-}
-
-func (h TxHash) Equals(other merkletree.Content) (bool, error) {
-	equals := bytes.Equal(h.hash, other.(TxHash).hash)
-	return equals, nil
-}
-
 // Hashblock returns a SHA-256 of the header.
 func HashBlock(block *proto.Block) []byte {
 	return HashHeader(block.Header)
 }
 
+// HashHeader returns a SHA-256 of the canonical encoding of header.
 func HashHeader(header *proto.Header) []byte {
-	b, err := pb.Marshal(header)
-	if err != nil {
-		panic(err)
-	}
-	hash := sha256.Sum256(b)
-
+	hash := sha256.Sum256(EncodeHeader(header))
 	return hash[:]
 }
 
-func SignBlock(pk *crypto.PrivateKey, b *proto.Block) *crypto.Signature {
+// BlockSigHash returns the message the header of a block of the chain
+// chainID is signed over: a SHA-256 of the chain ID as length prefixed
+// bytes and the canonical encoding of the header. It differs from the
+// hash of the block so a block signed for a chain is not valid on
+// another one.
+func BlockSigHash(chainID string, header *proto.Header) []byte {
+	buf := appendBytes(nil, []byte(chainID))
+	buf = appendHeader(buf, header)
+	hash := sha256.Sum256(buf)
+	return hash[:]
+}
 
-	if len(b.Transactions) > 0 {
-		tree, err := GetMerleTree(b)
-		if err != nil {
-			panic(err)
-		}
-		b.Header.RootHash = tree.MerkleRoot()
-	}
+// SignBlock sets the merkle root of b and signs it with pk for the chain
+// chainID.
+func SignBlock(chainID string, pk *crypto.PrivateKey, b *proto.Block) *crypto.Signature {
+	b.Header.RootHash = MerkleRoot(b)
 
-	hash := HashBlock(b)
-	sig := pk.Sign(hash)
+	sig := pk.Sign(BlockSigHash(chainID, b.Header))
 
 	b.PublicKey = pk.Public().Bytes()
 	b.Signature = sig.Bytes()
@@ -64,53 +44,25 @@ func SignBlock(pk *crypto.PrivateKey, b *proto.Block) *crypto.Signature {
 	return sig
 }
 
-func VerifyBlock(b *proto.Block) bool {
-	if len(b.Transactions) > 0 {
-		if !VerifyRootHash(b) {
-			return false
-		}
-	}
-	if len(b.PublicKey) != crypto.PubKeyLen {
+// VerifyBlock reports whether b commits to its transactions and is
+// signed by its public key for the chain chainID.
+func VerifyBlock(chainID string, b *proto.Block) bool {
+	if !VerifyRootHash(b) {
 		return false
 	}
-	if len(b.Signature) != crypto.SignatureLen {
-		return false
-	}
-	sig := crypto.SignatureFromBytes(b.Signature)
-	pubKey := crypto.PublicKeyFromBytes(b.PublicKey)
-	hash := HashBlock(b)
-
-	return sig.Verify(pubKey, hash)
-}
-
-func VerifyRootHash(b *proto.Block) bool {
-	tree, err := GetMerleTree(b)
+	sig, err := crypto.SignatureFromBytes(b.Signature)
 	if err != nil {
 		return false
 	}
-	valid, err := tree.VerifyTree()
+	pubKey, err := crypto.PublicKeyFromBytes(b.PublicKey)
 	if err != nil {
 		return false
 	}
-
-	if !valid {
-		return false
-	}
-
-	return bytes.Equal(b.Header.RootHash, tree.MerkleRoot())
+	return sig.Verify(pubKey, BlockSigHash(chainID, b.Header))
 }
 
-func GetMerleTree(b *proto.Block) (*merkletree.MerkleTree, error) {
-
-	list := make([]merkletree.Content, len(b.Transactions))
-	for i := 0; i < len(b.Transactions); i++ {
-		list[i] = NewTxHash(HashTransaction(b.Transactions[i]))
-	}
-
-	t, err := merkletree.NewTree(list)
-	if err != nil {
-		return nil, err
-	}
-
-	return t, nil
+// VerifyRootHash reports whether the header of b commits to its
+// transactions.
+func VerifyRootHash(b *proto.Block) bool {
+	return bytes.Equal(b.Header.GetRootHash(), MerkleRoot(b))
 }
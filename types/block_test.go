@@ -23,19 +23,20 @@ func TestSignVerifyBlock(t *testing.T) {
 		pubKey  = privKey.Public()
 	)
 
-	sig := SignBlock(privKey, block)
+	sig := SignBlock(testChainID, privKey, block)
 	assert.Equal(t, 64, len(sig.Bytes()))
-	assert.True(t, sig.Verify(pubKey, HashBlock(block)))
+	assert.True(t, sig.Verify(pubKey, BlockSigHash(testChainID, block.Header)))
+	assert.False(t, sig.Verify(pubKey, HashBlock(block)))
 
 	assert.Equal(t, block.PublicKey, pubKey.Bytes())
 	assert.Equal(t, block.Signature, sig.Bytes())
 
-	assert.True(t, VerifyBlock(block))
+	assert.True(t, VerifyBlock(testChainID, block))
 
 	invalidPRivKey := crypto.GeneratePrivateKey()
 	block.PublicKey = invalidPRivKey.Public().Bytes()
 
-	assert.False(t, VerifyBlock(block))
+	assert.False(t, VerifyBlock(testChainID, block))
 }
 
 func TestCalculateRootHash(t *testing.T) {
@@ -45,7 +46,7 @@ func TestCalculateRootHash(t *testing.T) {
 		Version: 1,
 	}
 	block.Transactions = append(block.Transactions, tx)
-	SignBlock(privKey, block)
+	SignBlock(testChainID, privKey, block)
 
 	assert.True(t, VerifyRootHash(block))
 	assert.Equal(t, 32, len(block.Header.RootHash))
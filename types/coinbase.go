@@ -0,0 +1,53 @@
+package types
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+
+	"github.com/DenisBytes/GoChain/proto"
+)
+
+// CoinbaseOutIndex is the PrevOutIndex of the single input of a coinbase
+// transaction. That input does not spend anything, its PrevTxHash is all
+// zeros and its Signature holds the height of the block, which keeps
+// the coinbase transactions of different blocks apart.
+const CoinbaseOutIndex = math.MaxUint32
+
+var coinbasePrevHash = make([]byte, 32)
+
+// NewCoinbaseTransaction creates the transaction minting the block
+// reward at the given height.
+func NewCoinbaseTransaction(height int, outputs ...*proto.TxOutput) *proto.Transaction {
+	heightBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(heightBytes, uint64(height))
+
+	return &proto.Transaction{
+		Version: 1,
+		Inputs: []*proto.TxInput{
+			{
+				PrevTxHash:   coinbasePrevHash,
+				PrevOutIndex: CoinbaseOutIndex,
+				Signature:    heightBytes,
+			},
+		},
+		Outputs: outputs,
+	}
+}
+
+func IsCoinbase(tx *proto.Transaction) bool {
+	if len(tx.Inputs) != 1 {
+		return false
+	}
+	input := tx.Inputs[0]
+	return input.PrevOutIndex == CoinbaseOutIndex && bytes.Equal(input.PrevTxHash, coinbasePrevHash)
+}
+
+// CoinbaseHeight returns the block height committed in a coinbase
+// transaction, or -1 if there is none.
+func CoinbaseHeight(tx *proto.Transaction) int {
+	if !IsCoinbase(tx) || len(tx.Inputs[0].Signature) != 8 {
+		return -1
+	}
+	return int(binary.BigEndian.Uint64(tx.Inputs[0].Signature))
+}
@@ -0,0 +1,91 @@
+package types
+
+import (
+	"encoding/binary"
+
+	"github.com/DenisBytes/GoChain/proto"
+)
+
+// The canonical encoding is what hashes and signatures are computed
+// over. Protobuf output is not canonical, so every implementation
+// encodes the fields itself, in the order listed below:
+//
+//	integer      fixed width, big endian, signed integers as two's complement
+//	bool         one byte, 0 or 1
+//	bytes        uint32 length, then the bytes
+//	list         uint32 count, then every element
+//
+//	Header       version int32, height int32, prevHash bytes,
+//	             rootHash bytes, timestamp int64
+//	TxInput      prevTxHash bytes, prevOutIndex uint32, publicKey bytes,
+//	             signature bytes
+//	TxOutput     amount int64, address bytes
+//	Transaction  version int32, inputs list, outputs list, replaceable bool
+//
+// A nil field and an empty one encode the same, and fields unknown to
+// this version are not encoded at all.
+
+// EncodeHeader returns the canonical encoding of header.
+func EncodeHeader(header *proto.Header) []byte {
+	return appendHeader(nil, header)
+}
+
+// EncodeTransaction returns the canonical encoding of tx.
+func EncodeTransaction(tx *proto.Transaction) []byte {
+	return appendTransaction(nil, tx)
+}
+
+// EncodeTxInput returns the canonical encoding of input.
+func EncodeTxInput(input *proto.TxInput) []byte {
+	return appendTxInput(nil, input)
+}
+
+// EncodeTxOutput returns the canonical encoding of output.
+func EncodeTxOutput(output *proto.TxOutput) []byte {
+	return appendTxOutput(nil, output)
+}
+
+func appendHeader(buf []byte, header *proto.Header) []byte {
+	buf = binary.BigEndian.AppendUint32(buf, uint32(header.GetVersion()))
+	buf = binary.BigEndian.AppendUint32(buf, uint32(header.GetHeight()))
+	buf = appendBytes(buf, header.GetPrevHash())
+	buf = appendBytes(buf, header.GetRootHash())
+	return binary.BigEndian.AppendUint64(buf, uint64(header.GetTimestamp()))
+}
+
+func appendTransaction(buf []byte, tx *proto.Transaction) []byte {
+	buf = binary.BigEndian.AppendUint32(buf, uint32(tx.GetVersion()))
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(tx.GetInputs())))
+	for _, input := range tx.GetInputs() {
+		buf = appendTxInput(buf, input)
+	}
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(tx.GetOutputs())))
+	for _, output := range tx.GetOutputs() {
+		buf = appendTxOutput(buf, output)
+	}
+	return appendBool(buf, tx.GetReplaceable())
+}
+
+func appendTxInput(buf []byte, input *proto.TxInput) []byte {
+	buf = appendBytes(buf, input.GetPrevTxHash())
+	buf = binary.BigEndian.AppendUint32(buf, input.GetPrevOutIndex())
+	buf = appendBytes(buf, input.GetPublicKey())
+	return appendBytes(buf, input.GetSignature())
+}
+
+func appendTxOutput(buf []byte, output *proto.TxOutput) []byte {
+	buf = binary.BigEndian.AppendUint64(buf, uint64(output.GetAmount()))
+	return appendBytes(buf, output.GetAddress())
+}
+
+func appendBytes(buf, b []byte) []byte {
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(b)))
+	return append(buf, b...)
+}
+
+func appendBool(buf []byte, b bool) []byte {
+	if b {
+		return append(buf, 1)
+	}
+	return append(buf, 0)
+}
@@ -0,0 +1,186 @@
+package types
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	pb "google.golang.org/protobuf/proto"
+
+	"github.com/DenisBytes/GoChain/proto"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+const encodingVectorsFile = "encoding.json"
+
+// encodingVector is a golden test vector: a message in its protobuf JSON
+// form, its canonical encoding and, for headers and transactions, its
+// hash, both in hex.
+type encodingVector struct {
+	Name     string          `json:"name"`
+	Type     string          `json:"type"`
+	Value    json.RawMessage `json:"value"`
+	Encoding string          `json:"encoding"`
+	Hash     string          `json:"hash,omitempty"`
+}
+
+func fill(b byte, n int) []byte {
+	return bytes.Repeat([]byte{b}, n)
+}
+
+func encodingCases() []struct {
+	name string
+	msg  pb.Message
+} {
+	input := &proto.TxInput{
+		PrevTxHash:   fill(0x11, 32),
+		PrevOutIndex: 1,
+		PublicKey:    fill(0x22, 32),
+		Signature:    fill(0x33, 64),
+	}
+	output := &proto.TxOutput{
+		Amount:  1000,
+		Address: fill(0x44, 20),
+	}
+	return []struct {
+		name string
+		msg  pb.Message
+	}{
+		{"empty header", &proto.Header{}},
+		{"header", &proto.Header{
+			Version:   1,
+			Height:    42,
+			PrevHash:  fill(0xaa, 32),
+			RootHash:  fill(0xbb, 32),
+			Timestamp: 1700000000000000000,
+		}},
+		{"header with negative fields", &proto.Header{
+			Version:   -1,
+			Height:    math.MaxInt32,
+			Timestamp: -1,
+		}},
+		{"empty input", &proto.TxInput{}},
+		{"input", input},
+		{"unsigned input", &proto.TxInput{
+			PrevTxHash:   fill(0x11, 32),
+			PrevOutIndex: math.MaxUint32,
+			PublicKey:    fill(0x22, 32),
+		}},
+		{"empty output", &proto.TxOutput{}},
+		{"output", output},
+		{"empty transaction", &proto.Transaction{}},
+		{"coinbase transaction", NewCoinbaseTransaction(7, output)},
+		{"transaction", &proto.Transaction{
+			Version: 1,
+			Inputs:  []*proto.TxInput{input, {PrevTxHash: fill(0x55, 32), PublicKey: fill(0x66, 32), Signature: fill(0x77, 64)}},
+			Outputs: []*proto.TxOutput{output, {Amount: 5, Address: fill(0x88, 20)}},
+		}},
+		{"replaceable transaction", &proto.Transaction{
+			Version:     1,
+			Inputs:      []*proto.TxInput{input},
+			Outputs:     []*proto.TxOutput{output},
+			Replaceable: true,
+		}},
+	}
+}
+
+// encode returns the type name, canonical encoding and hash of msg.
+func encode(msg pb.Message) (string, []byte, []byte) {
+	switch msg := msg.(type) {
+	case *proto.Header:
+		return "header", EncodeHeader(msg), HashHeader(msg)
+	case *proto.Transaction:
+		return "transaction", EncodeTransaction(msg), HashTransaction(msg)
+	case *proto.TxInput:
+		return "input", EncodeTxInput(msg), nil
+	case *proto.TxOutput:
+		return "output", EncodeTxOutput(msg), nil
+	}
+	panic("no canonical encoding")
+}
+
+func newMessage(typ string) pb.Message {
+	switch typ {
+	case "header":
+		return &proto.Header{}
+	case "transaction":
+		return &proto.Transaction{}
+	case "input":
+		return &proto.TxInput{}
+	case "output":
+		return &proto.TxOutput{}
+	}
+	return nil
+}
+
+func writeEncodingVectors(t *testing.T, path string) {
+	var vectors []encodingVector
+	for _, c := range encodingCases() {
+		value, err := protojson.Marshal(c.msg)
+		require.Nil(t, err)
+		// protojson output is deliberately unstable, compact it so the
+		// file only changes with the vectors.
+		var compact bytes.Buffer
+		require.Nil(t, json.Compact(&compact, value))
+
+		typ, encoding, hash := encode(c.msg)
+		vectors = append(vectors, encodingVector{
+			Name:     c.name,
+			Type:     typ,
+			Value:    compact.Bytes(),
+			Encoding: hex.EncodeToString(encoding),
+			Hash:     hex.EncodeToString(hash),
+		})
+	}
+	b, err := json.MarshalIndent(vectors, "", "  ")
+	require.Nil(t, err)
+	require.Nil(t, os.WriteFile(path, append(b, '\n'), 0o644))
+}
+
+func TestEncodingVectors(t *testing.T) {
+	path := filepath.Join("testdata", encodingVectorsFile)
+	if *update {
+		writeEncodingVectors(t, path)
+	}
+
+	b, err := os.ReadFile(path)
+	require.Nil(t, err)
+	var vectors []encodingVector
+	require.Nil(t, json.Unmarshal(b, &vectors))
+	require.Len(t, vectors, len(encodingCases()))
+
+	for _, vector := range vectors {
+		msg := newMessage(vector.Type)
+		require.NotNil(t, msg, vector.Name)
+		require.Nil(t, protojson.Unmarshal(vector.Value, msg), vector.Name)
+
+		_, encoding, hash := encode(msg)
+		require.Equal(t, vector.Encoding, hex.EncodeToString(encoding), vector.Name)
+		require.Equal(t, vector.Hash, hex.EncodeToString(hash), vector.Name)
+	}
+}
+
+func TestHashIgnoresUnknownFields(t *testing.T) {
+	tx := &proto.Transaction{
+		Version: 1,
+		Outputs: []*proto.TxOutput{{Amount: 10, Address: fill(0x44, 20)}},
+	}
+	b, err := pb.Marshal(tx)
+	require.Nil(t, err)
+
+	// Field 15 is not part of Transaction.
+	b = append(b, 0x78, 0x01)
+	decoded := &proto.Transaction{}
+	require.Nil(t, pb.Unmarshal(b, decoded))
+	require.NotEmpty(t, decoded.ProtoReflect().GetUnknown())
+
+	require.Equal(t, HashTransaction(tx), HashTransaction(decoded))
+}
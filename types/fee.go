@@ -0,0 +1,59 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/DenisBytes/GoChain/proto"
+)
+
+// ErrAmountOverflow is returned when amounts add up to more than an
+// int64 holds.
+var ErrAmountOverflow = errors.New("amount overflows")
+
+// AddAmounts returns a+b, or ErrAmountOverflow when the sum does not fit
+// in an int64.
+func AddAmounts(a, b int64) (int64, error) {
+	if (b > 0 && a > math.MaxInt64-b) || (b < 0 && a < math.MinInt64-b) {
+		return 0, fmt.Errorf("%w: %d + %d", ErrAmountOverflow, a, b)
+	}
+	return a + b, nil
+}
+
+// SumOutputs returns the total amount of the outputs of a transaction.
+func SumOutputs(tx *proto.Transaction) (int64, error) {
+	return sumAmounts(tx.Outputs)
+}
+
+func sumAmounts(outputs []*proto.TxOutput) (int64, error) {
+	sum := int64(0)
+	for _, output := range outputs {
+		var err error
+		if sum, err = AddAmounts(sum, output.Amount); err != nil {
+			return 0, err
+		}
+	}
+	return sum, nil
+}
+
+// TransactionFee returns the implicit fee of a transaction: what its
+// inputs spend minus what its outputs pay. spent holds the outputs the
+// inputs refer to, in the order of the inputs.
+func TransactionFee(tx *proto.Transaction, spent []*proto.TxOutput) (int64, error) {
+	if len(spent) != len(tx.Inputs) {
+		return 0, fmt.Errorf("got %d spent outputs for %d inputs", len(spent), len(tx.Inputs))
+	}
+	sumInputs, err := sumAmounts(spent)
+	if err != nil {
+		return 0, fmt.Errorf("inputs: %w", err)
+	}
+	sumOutputs, err := SumOutputs(tx)
+	if err != nil {
+		return 0, fmt.Errorf("outputs: %w", err)
+	}
+	if sumInputs < sumOutputs {
+		return 0, fmt.Errorf("insufficient balance got(%d) spending(%d)", sumInputs, sumOutputs)
+	}
+	return sumInputs - sumOutputs, nil
+}
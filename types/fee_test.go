@@ -0,0 +1,66 @@
+package types
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DenisBytes/GoChain/proto"
+	"github.com/DenisBytes/GoChain/util"
+)
+
+func TestTransactionFee(t *testing.T) {
+	tx := &proto.Transaction{
+		Version: 1,
+		Inputs: []*proto.TxInput{
+			{PrevTxHash: util.RandomHash(), PrevOutIndex: 0},
+			{PrevTxHash: util.RandomHash(), PrevOutIndex: 1},
+		},
+		Outputs: []*proto.TxOutput{
+			{Amount: 60},
+			{Amount: 30},
+		},
+	}
+	sum, err := SumOutputs(tx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(90), sum)
+
+	fee, err := TransactionFee(tx, []*proto.TxOutput{{Amount: 50}, {Amount: 50}})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(10), fee)
+
+	fee, err = TransactionFee(tx, []*proto.TxOutput{{Amount: 50}, {Amount: 40}})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), fee)
+
+	_, err = TransactionFee(tx, []*proto.TxOutput{{Amount: 50}, {Amount: 39}})
+	assert.NotNil(t, err)
+
+	_, err = TransactionFee(tx, []*proto.TxOutput{{Amount: 100}})
+	assert.NotNil(t, err)
+}
+
+func TestTransactionFeeOverflow(t *testing.T) {
+	tx := &proto.Transaction{
+		Version: 1,
+		Inputs:  []*proto.TxInput{{PrevTxHash: util.RandomHash()}},
+		// Wraps around to 1000 without the overflow checks.
+		Outputs: []*proto.TxOutput{{Amount: math.MaxInt64}, {Amount: math.MaxInt64}, {Amount: 1002}},
+	}
+	_, err := SumOutputs(tx)
+	assert.ErrorIs(t, err, ErrAmountOverflow)
+	_, err = TransactionFee(tx, []*proto.TxOutput{{Amount: 1000}})
+	assert.ErrorIs(t, err, ErrAmountOverflow)
+
+	tx.Inputs = append(tx.Inputs, &proto.TxInput{PrevTxHash: util.RandomHash()})
+	tx.Outputs = []*proto.TxOutput{{Amount: 10}}
+	_, err = TransactionFee(tx, []*proto.TxOutput{{Amount: math.MaxInt64}, {Amount: 1}})
+	assert.ErrorIs(t, err, ErrAmountOverflow)
+
+	sum, err := AddAmounts(math.MaxInt64-1, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(math.MaxInt64), sum)
+	_, err = AddAmounts(math.MinInt64, -1)
+	assert.ErrorIs(t, err, ErrAmountOverflow)
+}
@@ -0,0 +1,134 @@
+package types
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"github.com/DenisBytes/GoChain/proto"
+)
+
+// The merkle tree of a block has the hashes of its transactions as
+// leaves, in order. Leaves and internal nodes are hashed with different
+// prefixes so a node can never be passed off as a leaf:
+//
+//	leaf  = SHA-256(0x00 || tx hash)
+//	node  = SHA-256(0x01 || left || right)
+//
+// Each level pairs up the nodes of the level below from the left. A
+// node left without a pair at the end of an odd level is carried up
+// unchanged, it is not paired with itself. The root is the single node
+// of the top level, and the root of a block without transactions is
+// empty.
+const (
+	merkleLeafPrefix = 0x00
+	merkleNodePrefix = 0x01
+)
+
+var ErrTxNotInBlock = errors.New("transaction not in block")
+
+func merkleLeaf(txHash []byte) []byte {
+	hash := sha256.Sum256(append([]byte{merkleLeafPrefix}, txHash...))
+	return hash[:]
+}
+
+func merkleNode(left, right []byte) []byte {
+	buf := make([]byte, 0, 1+len(left)+len(right))
+	buf = append(buf, merkleNodePrefix)
+	buf = append(buf, left...)
+	buf = append(buf, right...)
+	hash := sha256.Sum256(buf)
+	return hash[:]
+}
+
+// nextLevel hashes the pairs of nodes of a level into the level above.
+func nextLevel(level [][]byte) [][]byte {
+	next := make([][]byte, 0, (len(level)+1)/2)
+	for i := 0; i < len(level); i += 2 {
+		if i+1 == len(level) {
+			next = append(next, level[i])
+			break
+		}
+		next = append(next, merkleNode(level[i], level[i+1]))
+	}
+	return next
+}
+
+func leafLevel(b *proto.Block) [][]byte {
+	level := make([][]byte, len(b.Transactions))
+	for i, tx := range b.Transactions {
+		level[i] = merkleLeaf(HashTransaction(tx))
+	}
+	return level
+}
+
+// MerkleRoot returns the root of the merkle tree of the transactions of
+// b.
+func MerkleRoot(b *proto.Block) []byte {
+	level := leafLevel(b)
+	if len(level) == 0 {
+		return nil
+	}
+	for len(level) > 1 {
+		level = nextLevel(level)
+	}
+	return level[0]
+}
+
+// BuildProof returns the proof that the transaction with the hash txHash
+// is included in b.
+func BuildProof(b *proto.Block, txHash []byte) (*proto.MerkleProof, error) {
+	index := -1
+	for i, tx := range b.Transactions {
+		if bytes.Equal(HashTransaction(tx), txHash) {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return nil, fmt.Errorf("%w: %x", ErrTxNotInBlock, txHash)
+	}
+
+	proof := &proto.MerkleProof{
+		Index:  uint32(index),
+		Leaves: uint32(len(b.Transactions)),
+	}
+	level := leafLevel(b)
+	for i := index; len(level) > 1; i /= 2 {
+		if sibling := i ^ 1; sibling < len(level) {
+			proof.Hashes = append(proof.Hashes, level[sibling])
+		}
+		level = nextLevel(level)
+	}
+	return proof, nil
+}
+
+// VerifyProof reports whether proof shows the transaction with the hash
+// txHash is included under root.
+func VerifyProof(root, txHash []byte, proof *proto.MerkleProof) bool {
+	if proof == nil || proof.Index >= proof.Leaves {
+		return false
+	}
+	var (
+		hash   = merkleLeaf(txHash)
+		hashes = proof.Hashes
+		index  = proof.Index
+	)
+	for width := proof.Leaves; width > 1; width = (width + 1) / 2 {
+		sibling := index ^ 1
+		if sibling < width {
+			if len(hashes) == 0 {
+				return false
+			}
+			if index%2 == 0 {
+				hash = merkleNode(hash, hashes[0])
+			} else {
+				hash = merkleNode(hashes[0], hash)
+			}
+			hashes = hashes[1:]
+		}
+		index /= 2
+	}
+	return len(hashes) == 0 && bytes.Equal(hash, root)
+}
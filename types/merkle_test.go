@@ -0,0 +1,77 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DenisBytes/GoChain/proto"
+	"github.com/DenisBytes/GoChain/util"
+)
+
+func blockWithTransactions(n int) *proto.Block {
+	block := util.RandomBlock()
+	for i := 0; i < n; i++ {
+		block.Transactions = append(block.Transactions, NewCoinbaseTransaction(i, &proto.TxOutput{
+			Amount:  int64(i + 1),
+			Address: util.RandomHash()[:20],
+		}))
+	}
+	return block
+}
+
+func TestMerkleRoot(t *testing.T) {
+	assert.Empty(t, MerkleRoot(blockWithTransactions(0)))
+
+	block := blockWithTransactions(3)
+	leaves := leafLevel(block)
+	// The leaves are not the transaction hashes themselves.
+	assert.NotEqual(t, HashTransaction(block.Transactions[0]), leaves[0])
+	assert.Equal(t, leaves[0], MerkleRoot(&proto.Block{Transactions: block.Transactions[:1]}))
+	// The odd leaf is carried up rather than paired with itself.
+	assert.Equal(t, merkleNode(merkleNode(leaves[0], leaves[1]), leaves[2]), MerkleRoot(block))
+
+	root := MerkleRoot(block)
+	block.Transactions[0], block.Transactions[1] = block.Transactions[1], block.Transactions[0]
+	assert.NotEqual(t, root, MerkleRoot(block))
+}
+
+func TestMerkleProofs(t *testing.T) {
+	for n := 1; n <= 9; n++ {
+		block := blockWithTransactions(n)
+		root := MerkleRoot(block)
+		for i, tx := range block.Transactions {
+			txHash := HashTransaction(tx)
+			proof, err := BuildProof(block, txHash)
+			require.Nil(t, err)
+			require.Equal(t, uint32(i), proof.Index)
+			require.True(t, VerifyProof(root, txHash, proof), "tx %d of %d", i, n)
+
+			require.False(t, VerifyProof(root, util.RandomHash(), proof))
+			require.False(t, VerifyProof(util.RandomHash(), txHash, proof))
+			if n > 1 {
+				moved := &proto.MerkleProof{Index: (proof.Index + 1) % proof.Leaves, Leaves: proof.Leaves, Hashes: proof.Hashes}
+				require.False(t, VerifyProof(root, txHash, moved))
+				require.False(t, VerifyProof(root, txHash, &proto.MerkleProof{Index: proof.Index, Leaves: proof.Leaves, Hashes: proof.Hashes[1:]}))
+			}
+		}
+	}
+
+	_, err := BuildProof(blockWithTransactions(3), util.RandomHash())
+	require.ErrorIs(t, err, ErrTxNotInBlock)
+	require.False(t, VerifyProof(nil, util.RandomHash(), &proto.MerkleProof{}))
+}
+
+func TestMerkleProofRejectsInternalNodes(t *testing.T) {
+	block := blockWithTransactions(4)
+	leaves := leafLevel(block)
+	left, right := merkleNode(leaves[0], leaves[1]), merkleNode(leaves[2], leaves[3])
+	root := MerkleRoot(block)
+	require.Equal(t, merkleNode(left, right), root)
+
+	// An internal node cannot be proven as a transaction of a smaller
+	// tree.
+	proof := &proto.MerkleProof{Index: 0, Leaves: 2, Hashes: [][]byte{right}}
+	assert.False(t, VerifyProof(root, left, proof))
+}
@@ -0,0 +1,146 @@
+package types
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/DenisBytes/GoChain/crypto"
+	"github.com/DenisBytes/GoChain/proto"
+)
+
+// SigHashType selects the parts of a transaction an input signature
+// commits to. It is appended to the signature as its last byte.
+type SigHashType byte
+
+const (
+	// SigHashAll commits to every input and every output.
+	SigHashAll SigHashType = 0x01
+	// SigHashSingle commits to every input and to the output with the
+	// index of the signed input only.
+	SigHashSingle SigHashType = 0x03
+	// SigHashAnyoneCanPay is combined with one of the above to commit to
+	// the signed input only, letting others add inputs.
+	SigHashAnyoneCanPay SigHashType = 0x80
+)
+
+// InputSignatureLen is the length of the signature of an input: the
+// signature itself followed by its SigHashType.
+const InputSignatureLen = crypto.SignatureLen + 1
+
+var (
+	ErrInvalidSigHashType = errors.New("invalid sighash type")
+	ErrNoSingleOutput     = errors.New("no output for a SIGHASH_SINGLE input")
+)
+
+func (t SigHashType) valid() bool {
+	base := t &^ SigHashAnyoneCanPay
+	return base == SigHashAll || base == SigHashSingle
+}
+
+// SigHash returns the message input index of tx is signed over on the
+// chain chainID. spent holds the outputs the inputs refer to, in the
+// order of the inputs.
+//
+// The message is a SHA-256 of the chain ID as length prefixed bytes and
+// the canonical encoding of a copy of tx with every signature blanked,
+// followed by the index of the input
+// within that copy as a uint32, the list of the outputs spent by the
+// inputs of the copy and the sighash type byte. SigHashAnyoneCanPay
+// keeps only the signed input in the copy, SigHashSingle only the
+// output with the index of the signed input.
+func SigHash(chainID string, tx *proto.Transaction, index int, spent []*proto.TxOutput, hashType SigHashType) ([]byte, error) {
+	if !hashType.valid() {
+		return nil, fmt.Errorf("%w: 0x%02x", ErrInvalidSigHashType, byte(hashType))
+	}
+	if index < 0 || index >= len(tx.Inputs) {
+		return nil, fmt.Errorf("input %d out of range", index)
+	}
+	if len(spent) != len(tx.Inputs) {
+		return nil, fmt.Errorf("got %d spent outputs for %d inputs", len(spent), len(tx.Inputs))
+	}
+
+	signed := &proto.Transaction{
+		Version:     tx.Version,
+		Outputs:     tx.Outputs,
+		Replaceable: tx.Replaceable,
+	}
+	if hashType&^SigHashAnyoneCanPay == SigHashSingle {
+		if index >= len(tx.Outputs) {
+			return nil, ErrNoSingleOutput
+		}
+		signed.Outputs = tx.Outputs[index : index+1]
+	}
+	inputs := tx.Inputs
+	if hashType&SigHashAnyoneCanPay != 0 {
+		inputs = inputs[index : index+1]
+		spent = spent[index : index+1]
+		index = 0
+	}
+	for _, input := range inputs {
+		signed.Inputs = append(signed.Inputs, &proto.TxInput{
+			PrevTxHash:   input.PrevTxHash,
+			PrevOutIndex: input.PrevOutIndex,
+			PublicKey:    input.PublicKey,
+		})
+	}
+
+	buf := appendBytes(nil, []byte(chainID))
+	buf = appendTransaction(buf, signed)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(index))
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(spent)))
+	for _, output := range spent {
+		buf = appendTxOutput(buf, output)
+	}
+	buf = append(buf, byte(hashType))
+
+	hash := sha256.Sum256(buf)
+	return hash[:], nil
+}
+
+// SignInput signs input index of tx for the chain chainID with pk over
+// its SigHash and sets
+// the signature of the input. The public keys of all the inputs are
+// signed over, so they must be set beforehand.
+func SignInput(chainID string, pk *crypto.PrivateKey, tx *proto.Transaction, index int, spent []*proto.TxOutput, hashType SigHashType) error {
+	if index < 0 || index >= len(tx.Inputs) {
+		return fmt.Errorf("input %d out of range", index)
+	}
+	if !bytes.Equal(tx.Inputs[index].PublicKey, pk.Public().Bytes()) {
+		return fmt.Errorf("input %d is not for the public key of the signer", index)
+	}
+	hash, err := SigHash(chainID, tx, index, spent, hashType)
+	if err != nil {
+		return err
+	}
+	tx.Inputs[index].Signature = append(pk.Sign(hash).Bytes(), byte(hashType))
+	return nil
+}
+
+// VerifyInput reports whether input index of tx carries a valid
+// signature by its public key for the chain chainID.
+func VerifyInput(chainID string, tx *proto.Transaction, index int, spent []*proto.TxOutput) bool {
+	if index < 0 || index >= len(tx.Inputs) {
+		return false
+	}
+	input := tx.Inputs[index]
+	if len(input.Signature) != InputSignatureLen {
+		return false
+	}
+	hashType := SigHashType(input.Signature[crypto.SignatureLen])
+	hash, err := SigHash(chainID, tx, index, spent, hashType)
+	if err != nil {
+		return false
+	}
+	sig, err := crypto.SignatureFromBytes(input.Signature[:crypto.SignatureLen])
+	if err != nil {
+		return false
+	}
+	pubKey, err := crypto.PublicKeyFromBytes(input.PublicKey)
+	if err != nil {
+		return false
+	}
+	return sig.Verify(pubKey, hash)
+}
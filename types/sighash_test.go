@@ -0,0 +1,146 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	pb "google.golang.org/protobuf/proto"
+
+	"github.com/DenisBytes/GoChain/crypto"
+	"github.com/DenisBytes/GoChain/proto"
+	"github.com/DenisBytes/GoChain/util"
+)
+
+// testChainID is the chain the tests sign transactions and blocks for.
+const testChainID = "gochain-test"
+
+// multiKeyTransaction returns a transaction with one input per key, each
+// spending an output owned by that key, and the spent outputs.
+func multiKeyTransaction(keys ...*crypto.PrivateKey) (*proto.Transaction, []*proto.TxOutput) {
+	tx := &proto.Transaction{Version: 1}
+	spent := []*proto.TxOutput{}
+	for i, key := range keys {
+		tx.Inputs = append(tx.Inputs, &proto.TxInput{
+			PrevTxHash:   util.RandomHash(),
+			PrevOutIndex: uint32(i),
+			PublicKey:    key.Public().Bytes(),
+		})
+		tx.Outputs = append(tx.Outputs, &proto.TxOutput{
+			Amount:  int64(90 + i),
+			Address: crypto.GeneratePrivateKey().Public().Address().Bytes(),
+		})
+		spent = append(spent, &proto.TxOutput{
+			Amount:  100,
+			Address: key.Public().Address().Bytes(),
+		})
+	}
+	return tx, spent
+}
+
+func TestSignInputsWithDifferentKeys(t *testing.T) {
+	keys := []*crypto.PrivateKey{crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey()}
+	tx, spent := multiKeyTransaction(keys...)
+
+	for i, key := range keys {
+		require.Nil(t, SignInput(testChainID, key, tx, i, spent, SigHashAll))
+		// Signing an input does not invalidate the ones signed before.
+		for j := 0; j <= i; j++ {
+			assert.True(t, VerifyInput(testChainID, tx, j, spent))
+		}
+	}
+	signed := pb.Clone(tx)
+	assert.True(t, VerifyTransaction(testChainID, tx, spent))
+	assert.True(t, pb.Equal(signed, tx))
+
+	// Signatures cannot be moved to another input.
+	tx.Inputs[0].Signature, tx.Inputs[1].Signature = tx.Inputs[1].Signature, tx.Inputs[0].Signature
+	assert.False(t, VerifyTransaction(testChainID, tx, spent))
+	tx.Inputs[0].Signature, tx.Inputs[1].Signature = tx.Inputs[1].Signature, tx.Inputs[0].Signature
+
+	// Every input commits to all the outputs and all the spent outputs.
+	tx.Outputs[2].Amount++
+	assert.False(t, VerifyInput(testChainID, tx, 0, spent))
+	tx.Outputs[2].Amount--
+
+	tampered := pb.Clone(spent[2]).(*proto.TxOutput)
+	tampered.Amount++
+	assert.False(t, VerifyInput(testChainID, tx, 0, []*proto.TxOutput{spent[0], spent[1], tampered}))
+	tampered = pb.Clone(spent[2]).(*proto.TxOutput)
+	tampered.Address = keys[0].Public().Address().Bytes()
+	assert.False(t, VerifyInput(testChainID, tx, 0, []*proto.TxOutput{spent[0], spent[1], tampered}))
+
+	assert.True(t, VerifyTransaction(testChainID, tx, spent))
+	assert.False(t, VerifyTransaction(testChainID, tx, spent[:2]))
+}
+
+func TestSigHashSingle(t *testing.T) {
+	keys := []*crypto.PrivateKey{crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey()}
+	tx, spent := multiKeyTransaction(keys...)
+	require.Nil(t, SignInput(testChainID, keys[0], tx, 0, spent, SigHashAll))
+	require.Nil(t, SignInput(testChainID, keys[1], tx, 1, spent, SigHashSingle))
+	assert.True(t, VerifyTransaction(testChainID, tx, spent))
+
+	// The other outputs may change without the SINGLE input noticing.
+	tx.Outputs[0].Amount++
+	assert.False(t, VerifyInput(testChainID, tx, 0, spent))
+	assert.True(t, VerifyInput(testChainID, tx, 1, spent))
+
+	tx.Outputs[1].Amount++
+	assert.False(t, VerifyInput(testChainID, tx, 1, spent))
+
+	// There must be an output to commit to.
+	tx.Outputs = tx.Outputs[:1]
+	require.ErrorIs(t, SignInput(testChainID, keys[1], tx, 1, spent, SigHashSingle), ErrNoSingleOutput)
+	assert.False(t, VerifyInput(testChainID, tx, 1, spent))
+}
+
+func TestSigHashAnyoneCanPay(t *testing.T) {
+	keys := []*crypto.PrivateKey{crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey()}
+	tx, spent := multiKeyTransaction(keys...)
+	require.Nil(t, SignInput(testChainID, keys[0], tx, 0, spent, SigHashAll|SigHashAnyoneCanPay))
+	require.Nil(t, SignInput(testChainID, keys[1], tx, 1, spent, SigHashAll))
+
+	// Someone else adds an input, only the ANYONECANPAY signature holds.
+	other := crypto.GeneratePrivateKey()
+	tx.Inputs = append(tx.Inputs, &proto.TxInput{PrevTxHash: util.RandomHash(), PublicKey: other.Public().Bytes()})
+	spent = append(spent, &proto.TxOutput{Amount: 50, Address: other.Public().Address().Bytes()})
+	require.Nil(t, SignInput(testChainID, other, tx, 2, spent, SigHashAll))
+
+	assert.True(t, VerifyInput(testChainID, tx, 0, spent))
+	assert.False(t, VerifyInput(testChainID, tx, 1, spent))
+	assert.True(t, VerifyInput(testChainID, tx, 2, spent))
+
+	// It still commits to every output.
+	tx.Outputs[1].Amount++
+	assert.False(t, VerifyInput(testChainID, tx, 0, spent))
+}
+
+func TestSignaturesCommitToChainID(t *testing.T) {
+	key := crypto.GeneratePrivateKey()
+	tx, spent := multiKeyTransaction(key)
+	require.Nil(t, SignTransaction(testChainID, key, tx, spent))
+	assert.True(t, VerifyTransaction(testChainID, tx, spent))
+	assert.False(t, VerifyTransaction("gochain-other", tx, spent))
+
+	block := util.RandomBlock()
+	SignBlock(testChainID, key, block)
+	assert.True(t, VerifyBlock(testChainID, block))
+	assert.False(t, VerifyBlock("gochain-other", block))
+}
+
+func TestInvalidSigHashType(t *testing.T) {
+	key := crypto.GeneratePrivateKey()
+	tx, spent := multiKeyTransaction(key)
+
+	_, err := SigHash(testChainID, tx, 0, spent, SigHashAnyoneCanPay)
+	require.ErrorIs(t, err, ErrInvalidSigHashType)
+	require.ErrorIs(t, SignInput(testChainID, key, tx, 0, spent, 0x02), ErrInvalidSigHashType)
+
+	require.Nil(t, SignInput(testChainID, key, tx, 0, spent, SigHashAll))
+	tx.Inputs[0].Signature[crypto.SignatureLen] = byte(SigHashSingle)
+	assert.False(t, VerifyInput(testChainID, tx, 0, spent))
+	tx.Inputs[0].Signature[crypto.SignatureLen] = 0x7f
+	assert.False(t, VerifyInput(testChainID, tx, 0, spent))
+	assert.False(t, VerifyInput(testChainID, tx, 1, spent))
+}
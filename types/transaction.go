@@ -5,31 +5,35 @@ import (
 
 	"github.com/DenisBytes/GoChain/crypto"
 	"github.com/DenisBytes/GoChain/proto"
-	pb "google.golang.org/protobuf/proto"
 )
 
+// HashTransaction returns a SHA-256 of the canonical encoding of tx.
 func HashTransaction(tx *proto.Transaction) []byte {
-	b, err := pb.Marshal(tx)
-	if err != nil {
-		panic(err)
-	}
-	hash := sha256.Sum256(b)
+	hash := sha256.Sum256(EncodeTransaction(tx))
 	return hash[:]
 }
 
-func SignTransaction(pk *crypto.PrivateKey, tx *proto.Transaction) *crypto.Signature {
-	return pk.Sign(HashTransaction(tx))
-}
-
-func VerifyTransaction(tx *proto.Transaction) bool {
+// SignTransaction sets pk as the public key of every input of tx and
+// signs them all for the chain chainID, committing to the whole
+// transaction. spent holds the outputs the inputs refer to, in the order
+// of the inputs.
+func SignTransaction(chainID string, pk *crypto.PrivateKey, tx *proto.Transaction, spent []*proto.TxOutput) error {
 	for _, input := range tx.Inputs {
-		if len(input.Signature) == 0 {
-			panic("the transaction has no signature")
+		input.PublicKey = pk.Public().Bytes()
+	}
+	for i := range tx.Inputs {
+		if err := SignInput(chainID, pk, tx, i, spent, SigHashAll); err != nil {
+			return err
 		}
-		sig := crypto.SignatureFromBytes(input.Signature)
-		pubKey := crypto.PublicKeyFromBytes(input.PublicKey)
-		input.Signature = nil
-		if !sig.Verify(pubKey, HashTransaction(tx)) {
+	}
+	return nil
+}
+
+// VerifyTransaction reports whether every input of tx is signed by its
+// public key for the chain chainID. It does not modify tx.
+func VerifyTransaction(chainID string, tx *proto.Transaction, spent []*proto.TxOutput) bool {
+	for i := range tx.Inputs {
+		if !VerifyInput(chainID, tx, i, spent) {
 			return false
 		}
 	}
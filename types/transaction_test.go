@@ -39,8 +39,41 @@ func TestNewTransaction(t *testing.T) {
 		Outputs: []*proto.TxOutput{output1, output2},
 	}
 
-	sig := SignTransaction(fromPrivKey, tx)
-	input.Signature = sig.Bytes()
+	spent := []*proto.TxOutput{{Amount: 100, Address: fromAddress}}
+	assert.Nil(t, SignTransaction(testChainID, fromPrivKey, tx, spent))
+	assert.Len(t, input.Signature, InputSignatureLen)
 
-	assert.True(t, VerifyTransaction(tx))
+	assert.True(t, VerifyTransaction(testChainID, tx, spent))
+	// Verifying leaves the signatures in place.
+	assert.Len(t, input.Signature, InputSignatureLen)
+	assert.True(t, VerifyTransaction(testChainID, tx, spent))
+}
+
+func TestCoinbaseTransaction(t *testing.T) {
+	address := crypto.GeneratePrivateKey().Public().Address().Bytes()
+	output := &proto.TxOutput{
+		Amount:  50,
+		Address: address,
+	}
+
+	tx := NewCoinbaseTransaction(7, output)
+	assert.True(t, IsCoinbase(tx))
+	assert.Equal(t, 7, CoinbaseHeight(tx))
+
+	// The same reward at another height is a different transaction.
+	other := NewCoinbaseTransaction(8, output)
+	assert.NotEqual(t, HashTransaction(tx), HashTransaction(other))
+
+	regular := &proto.Transaction{
+		Version: 1,
+		Inputs: []*proto.TxInput{
+			{
+				PrevTxHash:   util.RandomHash(),
+				PrevOutIndex: 0,
+			},
+		},
+		Outputs: []*proto.TxOutput{output},
+	}
+	assert.False(t, IsCoinbase(regular))
+	assert.Equal(t, -1, CoinbaseHeight(regular))
 }